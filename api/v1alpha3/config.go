@@ -1,5 +1,7 @@
 package v1alpha3
 
+import "time"
+
 type BOTConfigT struct {
 	Name           string                `yaml:"name"`
 	LogLevel       string                `yaml:"loglevel"`
@@ -7,6 +9,54 @@ type BOTConfigT struct {
 	ObjectWorker   ObjectWorkerConfigT   `yaml:"objectWorker"`
 	DatabaseWorker DatabaseWorkerConfigT `yaml:"databaseWorker"`
 	HashRingWorker HashRingWorkerConfigT `yaml:"hashringWorker,omitempty"`
+	JobWorker      JobWorkerConfigT      `yaml:"jobWorker,omitempty"`
+
+	PoolPersistence PoolPersistenceConfigT `yaml:"poolPersistence,omitempty"`
+	Shutdown        ShutdownConfigT        `yaml:"shutdown,omitempty"`
+}
+
+//--------------------------------------------------------------
+// SHUTDOWN CONFIG
+//--------------------------------------------------------------
+
+type ShutdownConfigT struct {
+	DrainTimeout time.Duration `yaml:"drainTimeout,omitempty"`
+	HandOff      bool          `yaml:"handOff,omitempty"`
+	FlushTimeout time.Duration `yaml:"flushTimeout,omitempty"`
+}
+
+//--------------------------------------------------------------
+// JOB WORKER CONFIG
+//--------------------------------------------------------------
+
+type JobWorkerConfigT struct {
+	LogLevel      string `yaml:"loglevel"`
+	PageSize      int    `yaml:"pageSize,omitempty"`
+	MaxPoolLength int    `yaml:"maxPoolLength,omitempty"`
+}
+
+//--------------------------------------------------------------
+// RETRY CONFIG
+//--------------------------------------------------------------
+
+type RetryConfigT struct {
+	MaxAttempts     int           `yaml:"maxAttempts,omitempty"`
+	InitialBackoff  time.Duration `yaml:"initialBackoff,omitempty"`
+	MaxBackoff      time.Duration `yaml:"maxBackoff,omitempty"`
+	Multiplier      float64       `yaml:"multiplier,omitempty"`
+	Jitter          float64       `yaml:"jitter,omitempty"`
+	RetryableErrors []string      `yaml:"retryableErrors,omitempty"`
+}
+
+//--------------------------------------------------------------
+// POOL PERSISTENCE CONFIG
+//--------------------------------------------------------------
+
+type PoolPersistenceConfigT struct {
+	Enabled            bool          `yaml:"enabled"`
+	Directory          string        `yaml:"directory"`
+	SyncWrites         bool          `yaml:"syncWrites,omitempty"`
+	CompactionInterval time.Duration `yaml:"compactionInterval,omitempty"`
 }
 
 //--------------------------------------------------------------
@@ -14,9 +64,11 @@ type BOTConfigT struct {
 //--------------------------------------------------------------
 
 type APIServiceConfigT struct {
-	LogLevel string `yaml:"loglevel"`
-	Address  string `yaml:"address"`
-	Port     string `yaml:"port"`
+	LogLevel                string        `yaml:"loglevel"`
+	Address                 string        `yaml:"address"`
+	Port                    string        `yaml:"port"`
+	TransferStatusRetention time.Duration `yaml:"transferStatusRetention,omitempty"`
+	BatchRequestTimeout     time.Duration `yaml:"batchRequestTimeout,omitempty"`
 }
 
 //--------------------------------------------------------------
@@ -24,21 +76,28 @@ type APIServiceConfigT struct {
 //--------------------------------------------------------------
 
 type ObjectWorkerConfigT struct {
-	LogLevel              string            `yaml:"loglevel"`
-	MaxChildTheads        int               `yaml:"maxChildTheads,omitempty"`
-	RequestsByChildThread int               `yaml:"requestsByChildThread,omitempty"`
-	Sources               []SourceConfigT   `yaml:"sources"`
-	Modifiers             []ModifierConfigT `yaml:"modifiers"`
-	Routing               RoutingConfigT    `yaml:"routing"`
+	LogLevel              string              `yaml:"loglevel"`
+	MaxChildTheads        int                 `yaml:"maxChildTheads,omitempty"`
+	RequestsByChildThread int                 `yaml:"requestsByChildThread,omitempty"`
+	Retry                 RetryConfigT        `yaml:"retry,omitempty"`
+	Verification          VerificationConfigT `yaml:"verification,omitempty"`
+	Sources               []SourceConfigT     `yaml:"sources"`
+	Modifiers             []ModifierConfigT   `yaml:"modifiers"`
+	Routing               RoutingConfigT      `yaml:"routing"`
 }
 
 // Sources
 
 type SourceConfigT struct {
-	Name string `yaml:"name"`
-	Type string `yaml:"type"`
-	S3   S3T    `yaml:"s3"`
-	GCS  GCST   `yaml:"gcs"`
+	Name       string      `yaml:"name"`
+	Type       string      `yaml:"type"`
+	S3         S3T         `yaml:"s3"`
+	GCS        GCST        `yaml:"gcs"`
+	Azure      AzureT      `yaml:"azure,omitempty"`
+	Filesystem FilesystemT `yaml:"filesystem,omitempty"`
+	HTTP       HTTPT       `yaml:"http,omitempty"`
+
+	Upload UploadConfigT `yaml:"upload,omitempty"`
 }
 
 type S3T struct {
@@ -53,6 +112,54 @@ type GCST struct {
 	CredentialsFile string `yaml:"credentialsFile"`
 }
 
+type AzureT struct {
+	AccountName string `yaml:"accountName"`
+	// Endpoint is the blob service URL, by default the account one in the public cloud
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// Auth is sharedKey, sas or workloadIdentity, by default the one of the credentials set
+	Auth       string `yaml:"auth,omitempty"`
+	AccountKey string `yaml:"accountKey,omitempty"`
+	SASToken   string `yaml:"sasToken,omitempty"`
+}
+
+// HTTPT is a read-only origin, with the object URL built from the template
+// replacing {bucket} and {path}
+type HTTPT struct {
+	URLTemplate string            `yaml:"urlTemplate"`
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	Username    string            `yaml:"username,omitempty"`
+	Password    string            `yaml:"password,omitempty"`
+	BearerToken string            `yaml:"bearerToken,omitempty"`
+	Timeout     time.Duration     `yaml:"timeout,omitempty"`
+	// MD5Header is the response header with the object md5, hex or base64 encoded,
+	// trusted besides the Content-MD5 header
+	MD5Header string `yaml:"md5Header,omitempty"`
+}
+
+// UploadConfigT sets how the s3, gcs and azure sources upload the objects bigger than the part size
+type UploadConfigT struct {
+	PartSize    int64 `yaml:"partSize,omitempty"`
+	Concurrency int   `yaml:"concurrency,omitempty"`
+	// Resumable keeps the uploaded parts of a failed upload to resume it in the next attempt
+	Resumable bool `yaml:"resumable,omitempty"`
+	// AbandonedUploadAge is the age of the unfinished uploads aborted by the cleanup,
+	// run every CleanupInterval
+	AbandonedUploadAge time.Duration `yaml:"abandonedUploadAge,omitempty"`
+	CleanupInterval    time.Duration `yaml:"cleanupInterval,omitempty"`
+}
+
+// FilesystemT is a local or NFS mounted directory, with a subdirectory by bucket
+type FilesystemT struct {
+	Directory string `yaml:"directory"`
+}
+
+// Verification
+
+type VerificationConfigT struct {
+	Enabled    bool     `yaml:"enabled"`
+	Algorithms []string `yaml:"algorithms,omitempty"`
+}
+
 // Modifiers
 type ModifierConfigT struct {
 	Name         string `yaml:"name"`
@@ -70,6 +177,7 @@ type RoutingConfigT struct {
 }
 
 type RouteConfigT struct {
+	Policy  string          `yaml:"policy,omitempty"`
 	Front   RouteObjConfigT `yaml:"front"`
 	Backend RouteObjConfigT `yaml:"backend"`
 }
@@ -84,19 +192,42 @@ type RouteObjConfigT struct {
 //--------------------------------------------------------------
 
 type DatabaseWorkerConfigT struct {
-	LogLevel              string    `yaml:"loglevel"`
-	MaxChildTheads        int       `yaml:"maxChildTheads,omitempty"`
-	RequestsByChildThread int       `yaml:"requestsByChildThread,omitempty"`
-	Database              DatabaseT `yaml:"database"`
+	LogLevel              string       `yaml:"loglevel"`
+	MaxChildTheads        int          `yaml:"maxChildTheads,omitempty"`
+	RequestsByChildThread int          `yaml:"requestsByChildThread,omitempty"`
+	Retry                 RetryConfigT `yaml:"retry,omitempty"`
+	Database              DatabaseT    `yaml:"database"`
 }
 
 type DatabaseT struct {
+	Type     string `yaml:"type,omitempty"`
 	Host     string `yaml:"host"`
 	Port     string `yaml:"port"`
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	Database string `yaml:"database"`
 	Table    string `yaml:"table"`
+
+	// RecordChecksum also records the checksum_algorithm and checksum columns
+	RecordChecksum bool             `yaml:"recordChecksum,omitempty"`
+	WriteMode      string           `yaml:"writeMode,omitempty"`
+	Columns        DatabaseColumnsT `yaml:"columns,omitempty"`
+}
+
+// DatabaseColumnsT maps the recorded fields to the table columns,
+// the fields without column are not recorded.
+type DatabaseColumnsT struct {
+	BlobPath          string `yaml:"blobPath,omitempty"`
+	MD5               string `yaml:"md5,omitempty"`
+	BucketName        string `yaml:"bucketName,omitempty"`
+	ChecksumAlgorithm string `yaml:"checksumAlgorithm,omitempty"`
+	Checksum          string `yaml:"checksum,omitempty"`
+	Size              string `yaml:"size,omitempty"`
+	ContentType       string `yaml:"contentType,omitempty"`
+	Source            string `yaml:"source,omitempty"`
+	TransferredAt     string `yaml:"transferredAt,omitempty"`
+	Instance          string `yaml:"instance,omitempty"`
+	UpdatedAt         string `yaml:"updatedAt,omitempty"`
 }
 
 //--------------------------------------------------------------
@@ -108,4 +239,56 @@ type HashRingWorkerConfigT struct {
 	LogLevel string `yaml:"loglevel"`
 	Proxy    string `yaml:"proxy"`
 	VNodes   int    `yaml:"vnodes"`
+
+	// Strategy assigns the objects to the instances with the hash function,
+	// and must be the same in every instance
+	Strategy     string `yaml:"strategy,omitempty"`
+	HashFunction string `yaml:"hashFunction,omitempty"`
+	// Weight is the share of objects of this instance relative to the other ones
+	Weight int `yaml:"weight,omitempty"`
+
+	// AdvertisedAddress and AdvertisedPort are the API address and port
+	// reachable by the other instances
+	AdvertisedAddress string        `yaml:"advertisedAddress,omitempty"`
+	AdvertisedPort    string        `yaml:"advertisedPort,omitempty"`
+	SelfCheckTimeout  time.Duration `yaml:"selfCheckTimeout,omitempty"`
+
+	Discovery HashRingDiscoveryConfigT `yaml:"discovery,omitempty"`
+}
+
+type HashRingDiscoveryConfigT struct {
+	// Type is the peers discovery backend: dns (looking up the proxy host), static or kubernetes
+	Type       string                     `yaml:"type,omitempty"`
+	Interval   time.Duration              `yaml:"interval,omitempty"`
+	Static     StaticDiscoveryConfigT     `yaml:"static,omitempty"`
+	Kubernetes KubernetesDiscoveryConfigT `yaml:"kubernetes,omitempty"`
+	Gossip     GossipDiscoveryConfigT     `yaml:"gossip,omitempty"`
+}
+
+type GossipDiscoveryConfigT struct {
+	BindAddress string `yaml:"bindAddress,omitempty"`
+	// Port is the gossip port, bound and advertised with the hashring advertised address
+	Port int `yaml:"port,omitempty"`
+	// Seeds are the gossip addresses (host:port) of the instances contacted to join the cluster
+	Seeds []string `yaml:"seeds,omitempty"`
+	// SecretKey is the base64 encoded key (16, 24 or 32 bytes) to encrypt the gossip messages, empty to disable it
+	SecretKey string `yaml:"secretKey,omitempty"`
+
+	// ProbeInterval and ProbeTimeout define the failure detection probes, and SuspicionMultiplier
+	// scales the time a member is suspected before being declared dead
+	ProbeInterval       time.Duration `yaml:"probeInterval,omitempty"`
+	ProbeTimeout        time.Duration `yaml:"probeTimeout,omitempty"`
+	SuspicionMultiplier int           `yaml:"suspicionMultiplier,omitempty"`
+	GossipInterval      time.Duration `yaml:"gossipInterval,omitempty"`
+}
+
+type StaticDiscoveryConfigT struct {
+	Addresses []string `yaml:"addresses"`
+}
+
+type KubernetesDiscoveryConfigT struct {
+	Namespace string `yaml:"namespace,omitempty"`
+	Service   string `yaml:"service"`
+	// Kubeconfig is the kubeconfig file used out of the cluster, empty to use the in-cluster credentials
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
 }
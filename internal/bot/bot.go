@@ -3,15 +3,20 @@ package bot
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"time"
 
 	"bot/api/v1alpha3"
 	"bot/internal/components/apiService"
 	"bot/internal/components/databaseWorker"
 	"bot/internal/components/hashringWorker"
+	"bot/internal/components/jobWorker"
 	"bot/internal/components/objectWorker"
 	"bot/internal/global"
 	"bot/internal/logger"
+	"bot/internal/managers/database"
+	"bot/internal/managers/hashring"
+	"bot/internal/managers/objectStorage"
 	"bot/internal/pools"
 )
 
@@ -23,25 +28,42 @@ type BotT struct {
 	ObjectWorker   *objectWorker.ObjectWorkerT
 	DatabaseWorker *databaseWorker.DatabaseWorkerT
 	HashringWorker *hashringWorker.HashringWorkerT
+	JobWorker      *jobWorker.JobWorkerT
+
+	objectPool *pools.ObjectRequestPoolT
+	dbPool     *pools.DatabaseRequestPoolT
+	statusPool *pools.TransferStatusPoolT
+	letterPool *pools.DeadLetterPoolT
+	jobPool    *pools.JobPoolT
 }
 
 // BOT SERVER FUNCTIONS
 
-func NewBotServer(configFilepath string) (botServer *BotT, err error) {
-	botConfig, err := parseConfig(configFilepath)
+// GetConfig returns the bot configuration in the file, checked and with the defaults set.
+func GetConfig(configFilepath string) (config v1alpha3.BOTConfigT, err error) {
+	config, err = parseConfig(configFilepath)
 	if err != nil {
-		return botServer, err
+		return config, err
 	}
 
-	botServer = &BotT{
-		config: botConfig,
+	b := &BotT{
+		config: config,
 	}
 
-	err = botServer.checkConfig()
+	err = b.checkConfig()
+	return b.config, err
+}
+
+func NewBotServer(configFilepath string) (botServer *BotT, err error) {
+	botConfig, err := GetConfig(configFilepath)
 	if err != nil {
 		return botServer, err
 	}
 
+	botServer = &BotT{
+		config: botConfig,
+	}
+
 	logCommon := global.GetLogCommonFields()
 	logCommon[global.LogFieldKeyCommonInstance] = botServer.config.Name
 	botServer.log = logger.NewLogger(context.Background(),
@@ -49,23 +71,49 @@ func NewBotServer(configFilepath string) (botServer *BotT, err error) {
 		logCommon,
 	)
 
-	dbPool := pools.NewDatabaseRequestPool()
-	objectPool := pools.NewObjectRequestPool()
+	// the history write mode records every transfer of the objects
+	botServer.dbPool = pools.NewDatabaseRequestPool(
+		botServer.config.DatabaseWorker.Database.WriteMode == database.WriteModeAppendHistory)
+	botServer.objectPool = pools.NewObjectRequestPool()
+	botServer.statusPool = pools.NewTransferStatusPool()
+	botServer.letterPool = pools.NewDeadLetterPool()
+	botServer.jobPool = pools.NewJobPool()
 	serverPool := pools.NewServerPool()
+	strategy, err := hashring.GetStrategy(botServer.config.HashRingWorker.Strategy,
+		botServer.config.HashRingWorker.HashFunction, botServer.config.HashRingWorker.VNodes)
+	if err != nil {
+		return botServer, err
+	}
+	ring := hashring.NewHashRing(strategy)
+
+	if botServer.config.PoolPersistence.Enabled {
+		err = botServer.setPoolJournals()
+		if err != nil {
+			return botServer, err
+		}
+	}
+
+	botServer.APIService = apiService.NewApiService(&botServer.config, botServer.objectPool, botServer.dbPool, botServer.statusPool, botServer.letterPool, botServer.jobPool)
 
-	botServer.APIService = apiService.NewApiService(&botServer.config, objectPool)
+	botServer.ObjectWorker, err = objectWorker.NewObjectWorker(&botServer.config, botServer.objectPool, botServer.dbPool, botServer.statusPool, botServer.letterPool, serverPool, ring)
+	if err != nil {
+		return botServer, err
+	}
 
-	botServer.ObjectWorker, err = objectWorker.NewObjectWorker(&botServer.config, objectPool, dbPool)
+	botServer.DatabaseWorker, err = databaseWorker.NewDatabaseWorker(&botServer.config, botServer.dbPool, botServer.statusPool, botServer.letterPool)
 	if err != nil {
 		return botServer, err
 	}
 
-	botServer.DatabaseWorker, err = databaseWorker.NewDatabaseWorker(&botServer.config, dbPool)
+	botServer.HashringWorker, err = hashringWorker.NewHashringWorker(&botServer.config, serverPool, ring)
 	if err != nil {
 		return botServer, err
 	}
 
-	botServer.HashringWorker = hashringWorker.NewHashringWorker(&botServer.config, serverPool)
+	botServer.JobWorker, err = jobWorker.NewJobWorker(&botServer.config, botServer.jobPool, botServer.objectPool, botServer.statusPool)
+	if err != nil {
+		return botServer, err
+	}
 
 	return botServer, err
 }
@@ -75,8 +123,13 @@ func (b *BotT) Run() {
 	b.HashringWorker.Run()
 	b.ObjectWorker.Run()
 	b.DatabaseWorker.Run()
+	b.JobWorker.Run()
 	b.APIService.Run()
 
+	if b.config.PoolPersistence.Enabled {
+		go b.poolsCompactionFlow()
+	}
+
 	for !global.ServerState.IsReady() {
 		b.log.Debug("waiting for bot server ready...", map[string]any{})
 		time.Sleep(5 * time.Second)
@@ -92,10 +145,150 @@ func (b *BotT) ShutdownActions(done chan bool, signal chan os.Signal) {
 		"signal": sig.String(),
 	})
 
+	// stop accepting requests, then drain the object requests, handing off
+	// the remaining ones, and flush the database requests they generated
 	b.APIService.Shutdown()
-	b.DatabaseWorker.Shutdown()
+	b.JobWorker.Shutdown()
 	b.ObjectWorker.Shutdown()
+	// the hashring stops changing before this instance leaves it to hand off the requests,
+	// so they are not forwarded back to it by a discovery or gossip update
+	b.HashringWorker.StopUpdates()
+	handedOff, objectRemaining := b.ObjectWorker.HandOff()
+	databaseRemaining := b.DatabaseWorker.Shutdown()
 	b.HashringWorker.Shutdown()
 
+	if b.config.PoolPersistence.Enabled {
+		b.compactPools()
+	}
+
+	logExtraFields := map[string]any{
+		"object_requests_handed_off":  handedOff,
+		"object_requests_remaining":   objectRemaining,
+		"database_requests_remaining": databaseRemaining,
+		"pool_persistence":            b.config.PoolPersistence.Enabled,
+	}
+	if (objectRemaining > 0 || databaseRemaining > 0) && !b.config.PoolPersistence.Enabled {
+		b.log.Warn("bot server shutdown with requests lost in pools, enable pool persistence to replay them", logExtraFields)
+	} else {
+		b.log.Info("bot server shutdown", logExtraFields)
+	}
+
 	done <- true
 }
+
+// POOL PERSISTENCE FUNCTIONS
+
+func (b *BotT) setPoolJournals() (err error) {
+	err = os.MkdirAll(b.config.PoolPersistence.Directory, 0o750)
+	if err != nil {
+		return err
+	}
+
+	objectJournal, err := pools.NewJournal(
+		filepath.Join(b.config.PoolPersistence.Directory, "objectRequests.journal"),
+		b.config.PoolPersistence.SyncWrites,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = b.objectPool.SetJournal(objectJournal)
+	if err != nil {
+		return err
+	}
+
+	dbJournal, err := pools.NewJournal(
+		filepath.Join(b.config.PoolPersistence.Directory, "databaseRequests.journal"),
+		b.config.PoolPersistence.SyncWrites,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = b.dbPool.SetJournal(dbJournal)
+	if err != nil {
+		return err
+	}
+
+	letterJournal, err := pools.NewJournal(
+		filepath.Join(b.config.PoolPersistence.Directory, "deadLetters.journal"),
+		b.config.PoolPersistence.SyncWrites,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = b.letterPool.SetJournal(letterJournal)
+	if err != nil {
+		return err
+	}
+
+	jobJournal, err := pools.NewJournal(
+		filepath.Join(b.config.PoolPersistence.Directory, "jobs.journal"),
+		b.config.PoolPersistence.SyncWrites,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = b.jobPool.SetJournal(jobJournal)
+	if err != nil {
+		return err
+	}
+
+	// restored requests are tracked again from the point they were persisted
+	for _, request := range b.objectPool.GetPool() {
+		b.statusPool.AddStatus(request.Id, request.Object)
+	}
+	for _, request := range b.dbPool.GetPool() {
+		b.statusPool.AddStatus(request.TransferId, objectStorage.ObjectT{
+			Bucket: request.BucketName,
+			Path:   request.ObjectPath,
+		})
+		b.statusPool.SetState(request.TransferId, pools.TransferStateCopied, "")
+	}
+
+	b.log.Info("pools restored from journals", map[string]any{
+		"object_pool_length":   len(b.objectPool.GetPool()),
+		"database_pool_length": len(b.dbPool.GetPool()),
+		"dead_letters_length":  len(b.letterPool.GetPool()),
+		"jobs_length":          len(b.jobPool.GetPool()),
+	})
+
+	return err
+}
+
+func (b *BotT) poolsCompactionFlow() {
+	for {
+		time.Sleep(b.config.PoolPersistence.CompactionInterval)
+		b.compactPools()
+	}
+}
+
+func (b *BotT) compactPools() {
+	if err := b.objectPool.Compact(); err != nil {
+		b.log.Error("unable to compact object request pool journal", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	if err := b.dbPool.Compact(); err != nil {
+		b.log.Error("unable to compact database request pool journal", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	if err := b.letterPool.Compact(); err != nil {
+		b.log.Error("unable to compact dead letter pool journal", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	if err := b.jobPool.Compact(); err != nil {
+		b.log.Error("unable to compact job pool journal", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	b.log.Debug("pool journals compacted", map[string]any{})
+}
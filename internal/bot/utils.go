@@ -3,8 +3,17 @@ package bot
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"bot/api/v1alpha3"
+	"bot/internal/global"
+	"bot/internal/managers/database"
+	"bot/internal/managers/discovery"
+	"bot/internal/managers/hashring"
+	"bot/internal/managers/objectStorage"
+	"bot/internal/managers/routing"
 
 	"gopkg.in/yaml.v3"
 )
@@ -35,6 +44,14 @@ func (b *BotT) checkConfig() (err error) {
 		b.config.APIService.Address = "0.0.0.0"
 	}
 
+	if b.config.APIService.TransferStatusRetention <= 0 {
+		b.config.APIService.TransferStatusRetention = 1 * time.Hour
+	}
+
+	if b.config.APIService.BatchRequestTimeout <= 0 {
+		b.config.APIService.BatchRequestTimeout = 5 * time.Minute
+	}
+
 	//--------------------------------------------------------------
 	// CHECK OBJECT CONFIG
 	//--------------------------------------------------------------
@@ -49,20 +66,106 @@ func (b *BotT) checkConfig() (err error) {
 		return err
 	}
 
+	for i, source := range b.config.ObjectWorker.Sources {
+		if !slices.Contains(objectStorage.Types, source.Type) {
+			err = fmt.Errorf("config option objectWorker.sources.%s.type must be one of: %v", source.Name, objectStorage.Types)
+			return err
+		}
+
+		err = checkUploadConfig(source.Name, source.Type, &b.config.ObjectWorker.Sources[i].Upload)
+		if err != nil {
+			return err
+		}
+
+		if source.Type == objectStorage.TypeAzure {
+			err = checkAzureSourceConfig(source.Name, &b.config.ObjectWorker.Sources[i].Azure)
+			if err != nil {
+				return err
+			}
+		}
+
+		if source.Type == objectStorage.TypeFilesystem && source.Filesystem.Directory == "" {
+			err = fmt.Errorf("config option objectWorker.sources.%s.filesystem.directory is empty", source.Name)
+			return err
+		}
+
+		if source.Type == objectStorage.TypeHTTP && !strings.Contains(source.HTTP.URLTemplate, objectStorage.HTTPTemplatePath) {
+			err = fmt.Errorf("config option objectWorker.sources.%s.http.urlTemplate must contain %s",
+				source.Name, objectStorage.HTTPTemplatePath)
+			return err
+		}
+	}
+
+	for routeKey, route := range b.config.ObjectWorker.Routing.Routes {
+		for _, source := range b.config.ObjectWorker.Sources {
+			if source.Name == route.Front.Source && slices.Contains(objectStorage.ReadOnlyTypes, source.Type) {
+				err = fmt.Errorf("config option objectWorker.routing.routes.%s.front.source can not be the read-only %s source '%s'",
+					routeKey, source.Type, source.Name)
+				return err
+			}
+		}
+
+		if !slices.Contains([]string{"", routing.PolicyOverwrite, routing.PolicySkipIfExists, routing.PolicySkipIfIdentical}, route.Policy) {
+			err = fmt.Errorf("config option objectWorker.routing.routes.%s.policy must be one of: %s, %s, %s",
+				routeKey, routing.PolicyOverwrite, routing.PolicySkipIfExists, routing.PolicySkipIfIdentical)
+			return err
+		}
+	}
+
+	if len(b.config.ObjectWorker.Verification.Algorithms) == 0 {
+		b.config.ObjectWorker.Verification.Algorithms = []string{objectStorage.ChecksumAlgorithmMD5}
+	}
+
+	for _, algorithm := range b.config.ObjectWorker.Verification.Algorithms {
+		if _, err = objectStorage.NewChecksumHash(algorithm); err != nil {
+			err = fmt.Errorf("config option objectWorker.verification.algorithms must contain only: %s, %s, %s",
+				objectStorage.ChecksumAlgorithmMD5, objectStorage.ChecksumAlgorithmCRC32C, objectStorage.ChecksumAlgorithmSHA256)
+			return err
+		}
+	}
+
+	err = checkRetryConfig("objectWorker.retry", &b.config.ObjectWorker.Retry)
+	if err != nil {
+		return err
+	}
+
 	//--------------------------------------------------------------
 	// CHECK DATABASE CONFIG
 	//--------------------------------------------------------------
 
-	if b.config.DatabaseWorker.Database.Host == "" {
-		err = fmt.Errorf("database host config is empty")
-		return err
+	if b.config.DatabaseWorker.Database.Type == "" {
+		b.config.DatabaseWorker.Database.Type = database.TypeMySQL
 	}
 
-	if b.config.DatabaseWorker.Database.Port == "" {
-		err = fmt.Errorf("database port config is empty")
+	if !slices.Contains([]string{database.TypeMySQL, database.TypePostgres, database.TypeSQLite}, b.config.DatabaseWorker.Database.Type) {
+		err = fmt.Errorf("config option databaseWorker.database.type must be one of: %s, %s, %s",
+			database.TypeMySQL, database.TypePostgres, database.TypeSQLite)
 		return err
 	}
 
+	// sqlite only needs the database file
+	if b.config.DatabaseWorker.Database.Type != database.TypeSQLite {
+		if b.config.DatabaseWorker.Database.Host == "" {
+			err = fmt.Errorf("database host config is empty")
+			return err
+		}
+
+		if b.config.DatabaseWorker.Database.Port == "" {
+			err = fmt.Errorf("database port config is empty")
+			return err
+		}
+
+		if b.config.DatabaseWorker.Database.Username == "" {
+			err = fmt.Errorf("database user config is empty")
+			return err
+		}
+
+		if b.config.DatabaseWorker.Database.Password == "" {
+			err = fmt.Errorf("database password config is empty")
+			return err
+		}
+	}
+
 	if b.config.DatabaseWorker.Database.Database == "" {
 		err = fmt.Errorf("database name config is empty")
 		return err
@@ -73,16 +176,40 @@ func (b *BotT) checkConfig() (err error) {
 		return err
 	}
 
-	if b.config.DatabaseWorker.Database.Username == "" {
-		err = fmt.Errorf("database user config is empty")
-		return err
+	if b.config.DatabaseWorker.Database.WriteMode == "" {
+		b.config.DatabaseWorker.Database.WriteMode = database.WriteModeInsertIgnore
 	}
 
-	if b.config.DatabaseWorker.Database.Password == "" {
-		err = fmt.Errorf("database password config is empty")
+	if !slices.Contains([]string{database.WriteModeInsertIgnore, database.WriteModeUpsert, database.WriteModeAppendHistory},
+		b.config.DatabaseWorker.Database.WriteMode) {
+		err = fmt.Errorf("config option databaseWorker.database.writeMode must be one of: %s, %s, %s",
+			database.WriteModeInsertIgnore, database.WriteModeUpsert, database.WriteModeAppendHistory)
 		return err
 	}
 
+	columns := &b.config.DatabaseWorker.Database.Columns
+	if columns.BlobPath == "" {
+		columns.BlobPath = "blob_path"
+	}
+
+	if columns.MD5 == "" {
+		columns.MD5 = "md5sum"
+	}
+
+	if columns.BucketName == "" {
+		columns.BucketName = "bucket_name"
+	}
+
+	if b.config.DatabaseWorker.Database.RecordChecksum {
+		if columns.ChecksumAlgorithm == "" {
+			columns.ChecksumAlgorithm = "checksum_algorithm"
+		}
+
+		if columns.Checksum == "" {
+			columns.Checksum = "checksum"
+		}
+	}
+
 	if b.config.DatabaseWorker.MaxChildTheads <= 0 {
 		err = fmt.Errorf("config option databaseWorker.maxChildTheads must be a number > 0")
 		return err
@@ -93,14 +220,263 @@ func (b *BotT) checkConfig() (err error) {
 		return err
 	}
 
+	err = checkRetryConfig("databaseWorker.retry", &b.config.DatabaseWorker.Retry)
+	if err != nil {
+		return err
+	}
+
 	//--------------------------------------------------------------
 	// CHECK HASHRING CONFIG
 	//--------------------------------------------------------------
 
+	if b.config.HashRingWorker.VNodes <= 0 {
+		b.config.HashRingWorker.VNodes = 128
+	}
+
+	if b.config.HashRingWorker.Strategy == "" {
+		b.config.HashRingWorker.Strategy = hashring.StrategyRing
+	}
+
+	if !slices.Contains(hashring.Strategies, b.config.HashRingWorker.Strategy) {
+		err = fmt.Errorf("config option hashringWorker.strategy must be one of: %v", hashring.Strategies)
+		return err
+	}
+
+	if b.config.HashRingWorker.HashFunction == "" {
+		b.config.HashRingWorker.HashFunction = hashring.HashFunctionXXHash
+	}
+
+	if !slices.Contains(hashring.HashFunctions, b.config.HashRingWorker.HashFunction) {
+		err = fmt.Errorf("config option hashringWorker.hashFunction must be one of: %v", hashring.HashFunctions)
+		return err
+	}
+
+	if b.config.HashRingWorker.Weight <= 0 {
+		b.config.HashRingWorker.Weight = 1
+	}
+
 	if b.config.HashRingWorker.Enabled {
-		if b.config.HashRingWorker.VNodes <= 0 {
-			b.config.HashRingWorker.VNodes = 1
+
+		if b.config.HashRingWorker.Discovery.Type == "" {
+			b.config.HashRingWorker.Discovery.Type = discovery.TypeDNS
+		}
+
+		if !slices.Contains([]string{discovery.TypeDNS, discovery.TypeStatic, discovery.TypeKubernetes, discovery.TypeGossip}, b.config.HashRingWorker.Discovery.Type) {
+			err = fmt.Errorf("config option hashringWorker.discovery.type must be one of: %s, %s, %s, %s",
+				discovery.TypeDNS, discovery.TypeStatic, discovery.TypeKubernetes, discovery.TypeGossip)
+			return err
+		}
+
+		if b.config.HashRingWorker.Discovery.Type == discovery.TypeDNS && b.config.HashRingWorker.Proxy == "" {
+			err = fmt.Errorf("config option hashringWorker.proxy is empty")
+			return err
+		}
+
+		if b.config.HashRingWorker.Discovery.Interval <= 0 {
+			b.config.HashRingWorker.Discovery.Interval = 2 * time.Second
+		}
+
+		if b.config.HashRingWorker.Discovery.Kubernetes.Namespace == "" {
+			b.config.HashRingWorker.Discovery.Kubernetes.Namespace = os.Getenv(global.EnvPodNamespace)
 		}
+
+		gossipConfig := &b.config.HashRingWorker.Discovery.Gossip
+		if gossipConfig.BindAddress == "" {
+			gossipConfig.BindAddress = "0.0.0.0"
+		}
+
+		if gossipConfig.Port <= 0 {
+			gossipConfig.Port = 7946
+		}
+
+		if gossipConfig.ProbeInterval <= 0 {
+			gossipConfig.ProbeInterval = 1 * time.Second
+		}
+
+		if gossipConfig.ProbeTimeout <= 0 {
+			gossipConfig.ProbeTimeout = 500 * time.Millisecond
+		}
+
+		if gossipConfig.SuspicionMultiplier <= 0 {
+			gossipConfig.SuspicionMultiplier = 4
+		}
+
+		if gossipConfig.GossipInterval <= 0 {
+			gossipConfig.GossipInterval = 200 * time.Millisecond
+		}
+
+		// the instances are identified in the hashring by name
+		if b.config.Name == "" {
+			b.config.Name = os.Getenv(global.EnvPodName)
+		}
+		if b.config.Name == "" {
+			b.config.Name, err = os.Hostname()
+			if err != nil {
+				err = fmt.Errorf("config option name is empty and unable to get hostname: %w", err)
+				return err
+			}
+		}
+
+		if b.config.HashRingWorker.AdvertisedAddress == "" {
+			b.config.HashRingWorker.AdvertisedAddress = os.Getenv(global.EnvPodIP)
+		}
+		if b.config.HashRingWorker.AdvertisedAddress == "" {
+			if b.config.APIService.Address == "0.0.0.0" || b.config.APIService.Address == "::" {
+				err = fmt.Errorf("config option hashringWorker.advertisedAddress is empty, with no %s environment variable and apiService.address listening in all interfaces", global.EnvPodIP)
+				return err
+			}
+			b.config.HashRingWorker.AdvertisedAddress = b.config.APIService.Address
+		}
+
+		if b.config.HashRingWorker.AdvertisedPort == "" {
+			b.config.HashRingWorker.AdvertisedPort = b.config.APIService.Port
+		}
+
+		if b.config.HashRingWorker.SelfCheckTimeout <= 0 {
+			b.config.HashRingWorker.SelfCheckTimeout = 1 * time.Minute
+		}
+	}
+
+	//--------------------------------------------------------------
+	// CHECK JOB CONFIG
+	//--------------------------------------------------------------
+
+	if b.config.JobWorker.PageSize <= 0 {
+		b.config.JobWorker.PageSize = 1000
+	}
+
+	if b.config.JobWorker.MaxPoolLength <= 0 {
+		b.config.JobWorker.MaxPoolLength = 10000
+	}
+
+	//--------------------------------------------------------------
+	// CHECK POOL PERSISTENCE CONFIG
+	//--------------------------------------------------------------
+
+	if b.config.PoolPersistence.Enabled {
+		if b.config.PoolPersistence.Directory == "" {
+			err = fmt.Errorf("config option poolPersistence.directory is empty")
+			return err
+		}
+
+		if b.config.PoolPersistence.CompactionInterval <= 0 {
+			b.config.PoolPersistence.CompactionInterval = 5 * time.Minute
+		}
+	}
+
+	//--------------------------------------------------------------
+	// CHECK SHUTDOWN CONFIG
+	//--------------------------------------------------------------
+
+	if b.config.Shutdown.DrainTimeout <= 0 {
+		b.config.Shutdown.DrainTimeout = 20 * time.Second
+	}
+
+	if b.config.Shutdown.FlushTimeout <= 0 {
+		b.config.Shutdown.FlushTimeout = 5 * time.Second
+	}
+
+	return err
+}
+
+func checkUploadConfig(name string, sourceType string, upload *v1alpha3.UploadConfigT) (err error) {
+	// the gcs uploads send the chunks in order and the azure ones can not resume the uploaded blocks
+	if sourceType == objectStorage.TypeGCS && upload.Concurrency > 0 {
+		err = fmt.Errorf("config option objectWorker.sources.%s.upload.concurrency is not supported by %s sources",
+			name, sourceType)
+		return err
+	}
+
+	if sourceType == objectStorage.TypeAzure && upload.Resumable {
+		err = fmt.Errorf("config option objectWorker.sources.%s.upload.resumable is not supported by %s sources",
+			name, sourceType)
+		return err
+	}
+
+	if upload.PartSize <= 0 {
+		upload.PartSize = 16 * 1024 * 1024
+	}
+
+	if upload.Concurrency <= 0 {
+		upload.Concurrency = 4
+	}
+
+	if upload.AbandonedUploadAge <= 0 {
+		upload.AbandonedUploadAge = 24 * time.Hour
+	}
+
+	if upload.CleanupInterval <= 0 {
+		upload.CleanupInterval = 1 * time.Hour
+	}
+
+	return err
+}
+
+func checkAzureSourceConfig(name string, azure *v1alpha3.AzureT) (err error) {
+	if azure.AccountName == "" {
+		err = fmt.Errorf("config option objectWorker.sources.%s.azure.accountName is empty", name)
+		return err
+	}
+
+	if azure.Auth == "" {
+		switch {
+		case azure.AccountKey != "":
+			azure.Auth = objectStorage.AzureAuthSharedKey
+		case azure.SASToken != "":
+			azure.Auth = objectStorage.AzureAuthSAS
+		default:
+			azure.Auth = objectStorage.AzureAuthWorkloadIdentity
+		}
+	}
+
+	if !slices.Contains(objectStorage.AzureAuths, azure.Auth) {
+		err = fmt.Errorf("config option objectWorker.sources.%s.azure.auth must be one of: %v", name, objectStorage.AzureAuths)
+		return err
+	}
+
+	if azure.Auth == objectStorage.AzureAuthSharedKey && azure.AccountKey == "" {
+		err = fmt.Errorf("config option objectWorker.sources.%s.azure.accountKey is empty", name)
+		return err
+	}
+
+	if azure.Auth == objectStorage.AzureAuthSAS && azure.SASToken == "" {
+		err = fmt.Errorf("config option objectWorker.sources.%s.azure.sasToken is empty", name)
+		return err
+	}
+
+	return err
+}
+
+func checkRetryConfig(option string, retry *v1alpha3.RetryConfigT) (err error) {
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = 1
+	}
+
+	if retry.InitialBackoff <= 0 {
+		retry.InitialBackoff = 1 * time.Second
+	}
+
+	if retry.MaxBackoff <= 0 {
+		retry.MaxBackoff = 1 * time.Minute
+	}
+
+	if retry.MaxBackoff < retry.InitialBackoff {
+		err = fmt.Errorf("config option %s.maxBackoff must be greater than %s.initialBackoff", option, option)
+		return err
+	}
+
+	if retry.Multiplier == 0 {
+		retry.Multiplier = 2
+	}
+
+	if retry.Multiplier < 1 {
+		err = fmt.Errorf("config option %s.multiplier must be a number >= 1", option)
+		return err
+	}
+
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		err = fmt.Errorf("config option %s.jitter must be a number between 0 and 1", option)
+		return err
 	}
 
 	return err
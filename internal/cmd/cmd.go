@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bot/internal/cmd/db"
 	"bot/internal/cmd/server"
 	"bot/internal/cmd/version"
 
@@ -23,6 +24,7 @@ func NewRootCommand(name string) *cobra.Command {
 	cmd.AddCommand(
 		version.NewCommand(),
 		server.NewCommand(),
+		db.NewCommand(),
 	)
 
 	return cmd
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"github.com/spf13/cobra"
+)
+
+const (
+	descriptionShort = `Manage the catalog database`
+	descriptionLong  = `
+	Db manage the catalog database table configured in the database worker`
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "db",
+		DisableFlagsInUseLine: true,
+		Short:                 descriptionShort,
+		Long:                  descriptionLong,
+	}
+
+	cmd.PersistentFlags().String(configFlagName, "config.yaml", "Bot service configuration")
+
+	cmd.AddCommand(
+		newMigrateCommand(),
+		newStatusCommand(),
+	)
+
+	return cmd
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	"bot/internal/bot"
+	"bot/internal/managers/database"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FLAG NAMES
+
+	configFlagName = `config`
+	dryRunFlagName = `dry-run`
+
+	// ERROR MESSAGES
+
+	configFlagErrMsg = "unable to get flag --config: %s"
+	dryRunFlagErrMsg = "unable to get flag --dry-run: %s"
+)
+
+type dbFlagsT struct {
+	config string
+	dryRun bool
+}
+
+func getFlags(cmd *cobra.Command) (flags dbFlagsT, err error) {
+
+	// Get db command flags
+
+	flags.config, err = cmd.Flags().GetString(configFlagName)
+	if err != nil {
+		log.Fatalf(configFlagErrMsg, err.Error())
+	}
+
+	if cmd.Flags().Lookup(dryRunFlagName) != nil {
+		flags.dryRun, err = cmd.Flags().GetBool(dryRunFlagName)
+		if err != nil {
+			log.Fatalf(dryRunFlagErrMsg, err.Error())
+		}
+	}
+
+	return flags, err
+}
+
+// getDatabaseManager returns the manager of the catalog database in the configuration file.
+func getDatabaseManager(configFilepath string) (manager database.DatabaseManagerI, err error) {
+	config, err := bot.GetConfig(configFilepath)
+	if err != nil {
+		return manager, err
+	}
+
+	manager, err = database.GetManager(context.Background(), config.DatabaseWorker.Database)
+	return manager, err
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	migrateDescriptionShort = `Create or upgrade the catalog table`
+	migrateDescriptionLong  = `
+	Migrate apply the catalog table migrations not applied yet,
+	only printing their statements in dry run mode`
+)
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "migrate",
+		DisableFlagsInUseLine: true,
+		Short:                 migrateDescriptionShort,
+		Long:                  migrateDescriptionLong,
+
+		Run: RunMigrateCommand,
+	}
+
+	cmd.Flags().Bool(dryRunFlagName, false, "Print the migration statements without applying them")
+
+	return cmd
+}
+
+func RunMigrateCommand(cmd *cobra.Command, args []string) {
+	flags, err := getFlags(cmd)
+	if err != nil {
+		log.Fatalf("unable to parse db migrate command flags")
+	}
+
+	manager, err := getDatabaseManager(flags.config)
+	if err != nil {
+		log.Fatalf("unable to config database manager: %s", err.Error())
+	}
+	defer manager.Close()
+
+	migrations, err := manager.Migrate(flags.dryRun)
+	for _, migration := range migrations {
+		fmt.Printf("migration %d: %s\n", migration.Version, migration.Description)
+		for _, statement := range migration.Statements {
+			fmt.Printf("  %s\n", statement)
+		}
+	}
+	if err != nil {
+		log.Fatalf("unable to migrate database: %s", err.Error())
+	}
+
+	switch {
+	case len(migrations) == 0:
+		fmt.Print("catalog table is up to date\n")
+	case flags.dryRun:
+		fmt.Printf("%d migrations pending (dry run)\n", len(migrations))
+	default:
+		fmt.Printf("%d migrations applied\n", len(migrations))
+	}
+}
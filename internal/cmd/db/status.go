@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	statusDescriptionShort = `Print the catalog table migrations status`
+	statusDescriptionLong  = `
+	Status print the catalog table migrations with their applied state`
+)
+
+func newStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "status",
+		DisableFlagsInUseLine: true,
+		Short:                 statusDescriptionShort,
+		Long:                  statusDescriptionLong,
+
+		Run: RunStatusCommand,
+	}
+
+	return cmd
+}
+
+func RunStatusCommand(cmd *cobra.Command, args []string) {
+	flags, err := getFlags(cmd)
+	if err != nil {
+		log.Fatalf("unable to parse db status command flags")
+	}
+
+	manager, err := getDatabaseManager(flags.config)
+	if err != nil {
+		log.Fatalf("unable to config database manager: %s", err.Error())
+	}
+	defer manager.Close()
+
+	statusList, err := manager.GetMigrationStatus()
+	if err != nil {
+		log.Fatalf("unable to get database migrations status: %s", err.Error())
+	}
+
+	for _, status := range statusList {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = fmt.Sprintf("applied at %s", status.AppliedAt)
+		}
+		fmt.Printf("migration %d: %s (%s)\n", status.Version, status.Description, appliedAt)
+	}
+}
@@ -3,6 +3,8 @@ package version
 import (
 	"fmt"
 
+	"bot/internal/global"
+
 	"github.com/spf13/cobra"
 )
 
@@ -27,5 +29,5 @@ func NewCommand() *cobra.Command {
 }
 
 func RunCommand(cmd *cobra.Command, args []string) {
-	fmt.Print("version: 0.1.0\n")
+	fmt.Printf("version: %s\n", global.Version)
 }
@@ -1,33 +1,75 @@
 package apiService
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"bot/api/v1alpha3"
 	"bot/internal/global"
 	"bot/internal/logger"
+	"bot/internal/managers/objectStorage"
+	"bot/internal/managers/routing"
 	"bot/internal/pools"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type APIServiceT struct {
 	config *v1alpha3.BOTConfigT
 	log    logger.LoggerT
 
-	ctx               context.Context
-	objectRequestPool *pools.ObjectRequestPoolT
-	httpServer        *http.Server
+	ctx                 context.Context
+	objectRequestPool   *pools.ObjectRequestPoolT
+	databaseRequestPool *pools.DatabaseRequestPoolT
+	transferStatusPool  *pools.TransferStatusPoolT
+	deadLetterPool      *pools.DeadLetterPoolT
+	jobPool             *pools.JobPoolT
+	router              *routing.RouterT
+	httpServer          *http.Server
+}
+
+type transferResponseT struct {
+	Id string `json:"id"`
+	objectStorage.ObjectT
+}
+
+const (
+	batchItemStatusAccepted = "accepted"
+	batchItemStatusRejected = "rejected"
+)
+
+type batchTransferResponseT struct {
+	Accepted int                  `json:"accepted"`
+	Rejected int                  `json:"rejected"`
+	Items    []batchItemResponseT `json:"items"`
+}
+
+type batchItemResponseT struct {
+	Index  int    `json:"index"`
+	Id     string `json:"id,omitempty"`
+	Bucket string `json:"bucket,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 // API REST Functions
 
-func NewApiService(config *v1alpha3.BOTConfigT, objectPool *pools.ObjectRequestPoolT) (a *APIServiceT) {
+func NewApiService(config *v1alpha3.BOTConfigT, objectPool *pools.ObjectRequestPoolT, dbPool *pools.DatabaseRequestPoolT,
+	statusPool *pools.TransferStatusPoolT, deadLetterPool *pools.DeadLetterPoolT, jobPool *pools.JobPoolT) (a *APIServiceT) {
 	a = &APIServiceT{
-		config:            config,
-		objectRequestPool: objectPool,
+		config:              config,
+		objectRequestPool:   objectPool,
+		databaseRequestPool: dbPool,
+		transferStatusPool:  statusPool,
+		deadLetterPool:      deadLetterPool,
+		jobPool:             jobPool,
+		router:              routing.NewRouter(&config.ObjectWorker),
 	}
 
 	logCommon := global.GetLogCommonFields()
@@ -42,8 +84,18 @@ func NewApiService(config *v1alpha3.BOTConfigT, objectPool *pools.ObjectRequestP
 	// Endpoints
 	mux.HandleFunc(global.EndpointHealthz, a.getHealthz)
 	mux.HandleFunc(global.EndpointInfo, a.getInfo)
-	mux.HandleFunc(global.EndpointRequestTransfer, a.postTransferRequest)
-	mux.HandleFunc(global.EndpointRequestObject, a.postTransferRequest)
+	mux.Handle(global.EndpointMetrics, promhttp.Handler())
+	mux.HandleFunc(global.EndpointRequestTransfer, instrument(global.EndpointRequestTransfer, a.postTransferRequest))
+	mux.HandleFunc(global.EndpointRequestTransferBatch, instrument(global.EndpointRequestTransferBatch, a.postTransferBatchRequest))
+	mux.HandleFunc(global.EndpointTransferStatus, instrument(global.EndpointTransferStatus, a.getTransferStatus))
+	mux.HandleFunc(global.EndpointDeadLetters, instrument(global.EndpointDeadLetters, a.handleDeadLetters))
+	mux.HandleFunc(global.EndpointDeadLetter, instrument(global.EndpointDeadLetter, a.handleDeadLetter))
+	mux.HandleFunc(global.EndpointDeadLetterRetry, instrument(global.EndpointDeadLetterRetry, a.postDeadLetterRetry))
+	mux.HandleFunc(global.EndpointJobs, instrument(global.EndpointJobs, a.handleJobs))
+	mux.HandleFunc(global.EndpointJob, instrument(global.EndpointJob, a.handleJob))
+	mux.HandleFunc(global.EndpointJobPause, instrument(global.EndpointJobPause, a.postJobPause))
+	mux.HandleFunc(global.EndpointJobResume, instrument(global.EndpointJobResume, a.postJobResume))
+	mux.HandleFunc(global.EndpointRequestObject, instrument(global.EndpointRequestObject, a.postTransferRequest))
 
 	a.ctx = context.Background()
 	a.httpServer = &http.Server{
@@ -61,6 +113,7 @@ func (a *APIServiceT) Run() {
 	logExtraFields := global.GetLogExtraFieldsAPI()
 
 	global.ServerState.SetAPIReady()
+	go a.transferStatusCleanupFlow()
 	go func() {
 		// service connections
 		if err := a.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -108,7 +161,10 @@ func (a *APIServiceT) getInfo(w http.ResponseWriter, r *http.Request) {
 
 	server := pools.ServerT{
 		Name:    a.config.Name,
-		Address: a.config.APIService.Address,
+		Address: a.config.HashRingWorker.AdvertisedAddress,
+		Port:    a.config.HashRingWorker.AdvertisedPort,
+		Version: global.Version,
+		Weight:  a.config.HashRingWorker.Weight,
 	}
 
 	w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
@@ -142,12 +198,349 @@ func (a *APIServiceT) postTransferRequest(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	a.objectRequestPool.AddRequest(objectRequest)
+	// the requests forwarded by other hashring instance are not forwarded again
+	objectRequest.Forwarded = r.Header.Get(global.HeaderForwardedBy) != ""
+
+	var err error
+	objectRequest, err = a.objectRequestPool.AddTrackedRequest(a.transferStatusPool, objectRequest)
+	logExtraFields[global.LogFieldKeyExtraTransferId] = objectRequest.Id
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		logExtraFields[global.LogFieldKeyExtraObject] = objectRequest.Object.String()
+		a.log.Error("unable to add object request in pool", logExtraFields)
+		return
+	}
 
 	w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(objectRequest.Object)
+	json.NewEncoder(w).Encode(transferResponseT{
+		Id:      objectRequest.Id,
+		ObjectT: objectRequest.Object,
+	})
 
 	logExtraFields[global.LogFieldKeyExtraObject] = objectRequest.Object.String()
 	a.log.Info("object request added in pool", logExtraFields)
 }
+
+// example:
+// curl -X POST
+// http://bot-host/transfer/batch --header "Content-Type: application/x-ndjson"
+// --data-binary
+// {"bucket":"backend-bucket","path":"path/to/object-1"}
+// {"bucket":"backend-bucket","path":"path/to/object-2"}
+//
+// a JSON array of objects is also accepted with "Content-Type: application/json"
+
+func (a *APIServiceT) postTransferBatchRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	// big batches need more time than the server default timeouts
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(a.config.APIService.BatchRequestTimeout)
+	err := rc.SetReadDeadline(deadline)
+	if err == nil {
+		err = rc.SetWriteDeadline(deadline)
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("unable to extend batch request deadlines", logExtraFields)
+		return
+	}
+
+	reader := bufio.NewReader(r.Body)
+	decoder := json.NewDecoder(reader)
+
+	// the body is a JSON array when it starts with '[', NDJSON otherwise
+	isArray := false
+	firstByte, err := peekFirstByte(reader)
+	if err != nil && err != io.EOF {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("object batch request decode error", logExtraFields)
+		return
+	}
+	if firstByte == '[' {
+		isArray = true
+		decoder.Token()
+	}
+
+	response := batchTransferResponseT{
+		Items: []batchItemResponseT{},
+	}
+	syntaxError := false
+	for index := 0; decoder.More(); index++ {
+		item := batchItemResponseT{
+			Index:  index,
+			Status: batchItemStatusRejected,
+		}
+
+		object := objectStorage.ObjectT{}
+		if err = decoder.Decode(&object); err != nil {
+			item.Error = err.Error()
+			response.Items = append(response.Items, item)
+			response.Rejected++
+
+			// the decoder can only continue after a type error
+			if _, ok := err.(*json.UnmarshalTypeError); ok {
+				continue
+			}
+			syntaxError = true
+			break
+		}
+		item.Bucket = object.Bucket
+		item.Path = object.Path
+
+		if item.Error = a.validateObjectRoute(object); item.Error == "" {
+			objectRequest, err := a.objectRequestPool.AddTrackedRequest(a.transferStatusPool, pools.ObjectRequestT{
+				Object:    object,
+				Forwarded: r.Header.Get(global.HeaderForwardedBy) != "",
+			})
+			item.Id = objectRequest.Id
+			if err != nil {
+				item.Error = err.Error()
+			}
+		}
+
+		if item.Error != "" {
+			response.Rejected++
+		} else {
+			item.Status = batchItemStatusAccepted
+			response.Accepted++
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	if isArray && !syntaxError {
+		if _, err = decoder.Token(); err != nil {
+			response.Items = append(response.Items, batchItemResponseT{
+				Index:  len(response.Items),
+				Status: batchItemStatusRejected,
+				Error:  err.Error(),
+			})
+			response.Rejected++
+		}
+	}
+
+	w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+
+	a.log.Info(fmt.Sprintf("object batch request with %d accepted and %d rejected objects",
+		response.Accepted, response.Rejected), logExtraFields)
+}
+
+// validateObjectRoute returns the reason why the object can not be routed,
+// or an empty string when the object has a valid route.
+func (a *APIServiceT) validateObjectRoute(object objectStorage.ObjectT) (reason string) {
+	if object.Bucket == "" || object.Path == "" {
+		return "empty bucket or path object"
+	}
+
+	if _, _, err := a.router.GetBackendObject(object); err != nil {
+		return err.Error()
+	}
+
+	if _, _, err := a.router.GetFrontendObject(object); err != nil {
+		return err.Error()
+	}
+
+	return reason
+}
+
+// example:
+// curl -X GET http://bot-host/transfer/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00
+
+func (a *APIServiceT) getTransferStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, ok := a.transferStatusPool.GetStatus(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// example:
+// curl -X GET http://bot-host/deadletters
+// curl -X DELETE http://bot-host/deadletters
+
+func (a *APIServiceT) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	switch r.Method {
+	case http.MethodGet:
+		{
+			letters := []pools.DeadLetterT{}
+			for _, letter := range a.deadLetterPool.GetPool() {
+				letters = append(letters, letter)
+			}
+
+			w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(letters)
+		}
+	case http.MethodDelete:
+		{
+			for id := range a.deadLetterPool.GetPool() {
+				if err := a.deadLetterPool.RemoveLetter(id); err != nil {
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+					logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+					a.log.Error("unable to purge dead letter pool", logExtraFields)
+					return
+				}
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			a.log.Info("dead letter pool purged", logExtraFields)
+		}
+	default:
+		{
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// example:
+// curl -X GET http://bot-host/deadletters/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00
+// curl -X DELETE http://bot-host/deadletters/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00
+
+func (a *APIServiceT) handleDeadLetter(w http.ResponseWriter, r *http.Request) {
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	id := r.PathValue("id")
+	letter, ok := a.deadLetterPool.GetLetter(id)
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	logExtraFields[global.LogFieldKeyExtraTransferId] = letter.Id
+
+	switch r.Method {
+	case http.MethodGet:
+		{
+			w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(letter)
+		}
+	case http.MethodDelete:
+		{
+			if err := a.deadLetterPool.RemoveLetter(id); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+				logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+				a.log.Error("unable to remove dead letter", logExtraFields)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			a.log.Info("dead letter removed", logExtraFields)
+		}
+	default:
+		{
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// example:
+// curl -X POST http://bot-host/deadletters/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00/retry
+//
+// the response has the transfer id of the retried request, a new one for the object requests
+
+func (a *APIServiceT) postDeadLetterRetry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	letter, ok := a.deadLetterPool.GetLetter(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	logExtraFields[global.LogFieldKeyExtraTransferId] = letter.Id
+
+	// the request goes back to its pool with all its attempts available, the object requests
+	// as a new transfer, superseding the pending request of the object as any other one
+	var err error
+	response := transferResponseT{}
+	switch letter.Kind {
+	case pools.DeadLetterKindObject:
+		{
+			var request pools.ObjectRequestT
+			request, err = a.objectRequestPool.AddTrackedRequest(a.transferStatusPool, pools.ObjectRequestT{
+				Object:    letter.ObjectRequest.Object,
+				Forwarded: letter.ObjectRequest.Forwarded,
+			})
+			response = transferResponseT{Id: request.Id, ObjectT: request.Object}
+		}
+	case pools.DeadLetterKindDatabase:
+		{
+			request := *letter.DatabaseRequest
+			request.Attempts = 0
+			request.NotBefore = time.Time{}
+			a.transferStatusPool.AddStatus(request.TransferId, objectStorage.ObjectT{
+				Bucket: request.BucketName,
+				Path:   request.ObjectPath,
+			})
+			a.transferStatusPool.SetState(request.TransferId, pools.TransferStateCopied, "")
+			err = a.databaseRequestPool.AddRequest(request)
+			response = transferResponseT{Id: request.TransferId, ObjectT: objectStorage.ObjectT{
+				Bucket: request.BucketName,
+				Path:   request.ObjectPath,
+			}}
+		}
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("unable to retry dead letter", logExtraFields)
+		return
+	}
+
+	if err = a.deadLetterPool.RemoveLetter(letter.Id); err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("unable to remove retried dead letter", logExtraFields)
+	}
+
+	w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+
+	logExtraFields[global.LogFieldKeyExtraTransferId] = response.Id
+	a.log.Info("dead letter moved to request pool to retry", logExtraFields)
+}
+
+func (a *APIServiceT) transferStatusCleanupFlow() {
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	for {
+		time.Sleep(a.config.APIService.TransferStatusRetention / 10)
+
+		count := a.transferStatusPool.RemoveExpired(a.config.APIService.TransferStatusRetention)
+		if count > 0 {
+			a.log.Debug(fmt.Sprintf("removed %d expired transfer status", count), logExtraFields)
+		}
+	}
+}
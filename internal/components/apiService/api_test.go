@@ -0,0 +1,249 @@
+package apiService
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bot/api/v1alpha3"
+	"bot/internal/managers/objectStorage"
+	"bot/internal/pools"
+)
+
+// getTestAPIService returns the api service with its pools and a server serving its endpoints,
+// with the objects of the bucket "bucket" routed to the backend.
+func getTestAPIService(t *testing.T) (a *APIServiceT, server *httptest.Server) {
+	t.Helper()
+
+	config := &v1alpha3.BOTConfigT{
+		APIService: v1alpha3.APIServiceConfigT{
+			LogLevel:            "error",
+			BatchRequestTimeout: 10 * time.Second,
+		},
+		ObjectWorker: v1alpha3.ObjectWorkerConfigT{
+			Modifiers: []v1alpha3.ModifierConfigT{
+				{Name: "front", Bucket: "front-bucket"},
+				{Name: "backend", Bucket: "backend-bucket"},
+			},
+			Routing: v1alpha3.RoutingConfigT{
+				Type: "bucket",
+				Routes: map[string]v1alpha3.RouteConfigT{
+					"bucket": {
+						Front:   v1alpha3.RouteObjConfigT{Source: "front", Modifiers: []string{"front"}},
+						Backend: v1alpha3.RouteObjConfigT{Source: "backend", Modifiers: []string{"backend"}},
+					},
+				},
+			},
+		},
+	}
+
+	a = NewApiService(config, pools.NewObjectRequestPool(), pools.NewDatabaseRequestPool(false),
+		pools.NewTransferStatusPool(), pools.NewDeadLetterPool(), pools.NewJobPool())
+	server = httptest.NewServer(a.httpServer.Handler)
+	t.Cleanup(server.Close)
+
+	return a, server
+}
+
+func TestPostTransferBatchRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+
+		expectedAccepted int
+		expectedStatuses []string
+	}{
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			body: `{"bucket":"bucket","path":"object-1"}` + "\n" +
+				`{"bucket":"bucket","path":"object-2"}` + "\n",
+			expectedAccepted: 2,
+			expectedStatuses: []string{batchItemStatusAccepted, batchItemStatusAccepted},
+		},
+		{
+			name:             "json array",
+			contentType:      "application/json",
+			body:             ` [{"bucket":"bucket","path":"object-1"}, {"bucket":"bucket","path":"object-2"}]`,
+			expectedAccepted: 2,
+			expectedStatuses: []string{batchItemStatusAccepted, batchItemStatusAccepted},
+		},
+		{
+			name:        "empty body",
+			contentType: "application/x-ndjson",
+			body:        "",
+		},
+		{
+			name:        "not routed and empty objects",
+			contentType: "application/x-ndjson",
+			body: `{"bucket":"other","path":"object-1"}` + "\n" +
+				`{"bucket":"bucket","path":""}` + "\n" +
+				`{"bucket":"bucket","path":"object-3"}` + "\n",
+			expectedAccepted: 1,
+			expectedStatuses: []string{batchItemStatusRejected, batchItemStatusRejected, batchItemStatusAccepted},
+		},
+		{
+			name:        "type error continues",
+			contentType: "application/x-ndjson",
+			body: `{"bucket":1,"path":"object-1"}` + "\n" +
+				`{"bucket":"bucket","path":"object-2"}` + "\n",
+			expectedAccepted: 1,
+			expectedStatuses: []string{batchItemStatusRejected, batchItemStatusAccepted},
+		},
+		{
+			name:        "syntax error stops",
+			contentType: "application/x-ndjson",
+			body: `{"bucket":"bucket","path":"object-1"}` + "\n" +
+				`{"bucket":"bucket",` + "\n" +
+				`{"bucket":"bucket","path":"object-3"}` + "\n",
+			expectedAccepted: 1,
+			expectedStatuses: []string{batchItemStatusAccepted, batchItemStatusRejected},
+		},
+		{
+			name:             "unclosed json array",
+			contentType:      "application/json",
+			body:             `[{"bucket":"bucket","path":"object-1"}`,
+			expectedAccepted: 1,
+			expectedStatuses: []string{batchItemStatusAccepted, batchItemStatusRejected},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, server := getTestAPIService(t)
+
+			res, err := http.Post(server.URL+"/transfer/batch", test.contentType, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatalf("unable to post batch: %s", err.Error())
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("response status is %d, expected %d", res.StatusCode, http.StatusOK)
+			}
+
+			response := batchTransferResponseT{}
+			if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+				t.Fatalf("unable to decode response: %s", err.Error())
+			}
+
+			if response.Accepted != test.expectedAccepted || response.Rejected != len(test.expectedStatuses)-test.expectedAccepted {
+				t.Fatalf("response has %d accepted and %d rejected items, expected %d and %d", response.Accepted,
+					response.Rejected, test.expectedAccepted, len(test.expectedStatuses)-test.expectedAccepted)
+			}
+			if len(response.Items) != len(test.expectedStatuses) {
+				t.Fatalf("response has %d items, expected %d: %+v", len(response.Items), len(test.expectedStatuses), response.Items)
+			}
+			for i, item := range response.Items {
+				if item.Index != i || item.Status != test.expectedStatuses[i] {
+					t.Fatalf("item %d is %+v, expected status '%s'", i, item, test.expectedStatuses[i])
+				}
+
+				// the accepted objects are tracked in the pools
+				if item.Status == batchItemStatusAccepted {
+					if _, ok := a.transferStatusPool.GetStatus(item.Id); !ok {
+						t.Fatalf("item %d transfer '%s' not tracked", i, item.Id)
+					}
+				}
+			}
+
+			if length := len(a.objectRequestPool.GetPool()); length != test.expectedAccepted {
+				t.Fatalf("object pool has %d requests, expected %d", length, test.expectedAccepted)
+			}
+		})
+	}
+}
+
+func TestPostDeadLetterRetry(t *testing.T) {
+	object := pools.ObjectRequestT{
+		Id:       "failed",
+		Object:   objectStorage.ObjectT{Bucket: "bucket", Path: "object"},
+		Attempts: 5,
+	}
+	database := pools.DatabaseRequestT{
+		TransferId: "failed",
+		BucketName: "backend-bucket",
+		ObjectPath: "object",
+		Attempts:   5,
+	}
+
+	tests := []struct {
+		name   string
+		letter pools.DeadLetterT
+
+		expectedNewId bool
+	}{
+		{
+			name:          "object request as new transfer",
+			letter:        pools.DeadLetterT{Id: "failed", Kind: pools.DeadLetterKindObject, ObjectRequest: &object},
+			expectedNewId: true,
+		},
+		{
+			name:   "database request with its transfer",
+			letter: pools.DeadLetterT{Id: "failed", Kind: pools.DeadLetterKindDatabase, DatabaseRequest: &database},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, server := getTestAPIService(t)
+			if err := a.deadLetterPool.AddLetter(test.letter); err != nil {
+				t.Fatalf("unable to add dead letter: %s", err.Error())
+			}
+
+			res, err := http.Post(server.URL+"/deadletters/failed/retry", "", nil)
+			if err != nil {
+				t.Fatalf("unable to post retry: %s", err.Error())
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusAccepted {
+				t.Fatalf("response status is %d, expected %d", res.StatusCode, http.StatusAccepted)
+			}
+
+			response := transferResponseT{}
+			if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+				t.Fatalf("unable to decode response: %s", err.Error())
+			}
+			if (response.Id != "failed") != test.expectedNewId || response.Id == "" {
+				t.Fatalf("response transfer id is '%s', new id expected %t", response.Id, test.expectedNewId)
+			}
+
+			if _, ok := a.deadLetterPool.GetLetter("failed"); ok {
+				t.Fatalf("retried dead letter kept in pool")
+			}
+
+			status, ok := a.transferStatusPool.GetStatus(response.Id)
+			if !ok {
+				t.Fatalf("transfer '%s' not tracked", response.Id)
+			}
+
+			// the requests are retried with all their attempts available
+			switch test.letter.Kind {
+			case pools.DeadLetterKindObject:
+				{
+					request, ok := a.objectRequestPool.GetRequest("bucket/object")
+					if !ok || request.Id != response.Id || request.Attempts != 0 || status.State != pools.TransferStateQueued {
+						t.Fatalf("object request %+v with state '%s' not queued as new transfer", request, status.State)
+					}
+				}
+			case pools.DeadLetterKindDatabase:
+				{
+					requests := a.databaseRequestPool.GetPool()
+					if len(requests) != 1 || status.State != pools.TransferStateCopied {
+						t.Fatalf("database requests %+v with state '%s' not queued", requests, status.State)
+					}
+					for _, request := range requests {
+						if request.Attempts != 0 || request.TransferId != "failed" {
+							t.Fatalf("database request %+v not reset", request)
+						}
+					}
+				}
+			}
+		})
+	}
+}
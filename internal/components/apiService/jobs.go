@@ -0,0 +1,205 @@
+package apiService
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"bot/api/v1alpha3"
+	"bot/internal/global"
+	"bot/internal/pools"
+)
+
+// example:
+// curl -X POST
+// http://bot-host/jobs --header "Content-Type: application/json"
+// --data
+// {
+// 	"source":"gcs-example",
+// 	"bucket":"backend-bucket",
+// 	"prefix":"path/to/",
+// 	"requestBucket":"bucket-name"
+// }
+//
+// curl -X GET http://bot-host/jobs
+
+func (a *APIServiceT) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		{
+			jobs := []pools.JobT{}
+			for _, job := range a.jobPool.GetPool() {
+				jobs = append(jobs, job)
+			}
+
+			w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(jobs)
+		}
+	case http.MethodPost:
+		{
+			a.postJob(w, r)
+		}
+	default:
+		{
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func (a *APIServiceT) postJob(w http.ResponseWriter, r *http.Request) {
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	request := pools.JobT{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("job request decode error", logExtraFields)
+		return
+	}
+
+	if err := a.validateJob(request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("invalid job request", logExtraFields)
+		return
+	}
+
+	now := time.Now()
+	job := pools.JobT{
+		Id:            pools.NewRequestId(),
+		Source:        request.Source,
+		Bucket:        request.Bucket,
+		Prefix:        request.Prefix,
+		RequestBucket: request.RequestBucket,
+		Metadata:      request.Metadata,
+		State:         pools.JobStateRunning,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if job.RequestBucket == "" {
+		job.RequestBucket = job.Bucket
+	}
+	logExtraFields[global.LogFieldKeyExtraObject] = job.String()
+
+	if err := a.jobPool.AddJob(job); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("unable to add job in pool", logExtraFields)
+		return
+	}
+
+	w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(job)
+
+	a.log.Info("job added in pool", logExtraFields)
+}
+
+// example:
+// curl -X GET http://bot-host/jobs/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00
+// curl -X DELETE http://bot-host/jobs/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00
+
+func (a *APIServiceT) handleJob(w http.ResponseWriter, r *http.Request) {
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	job, ok := a.jobPool.GetJob(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	logExtraFields[global.LogFieldKeyExtraObject] = job.String()
+
+	switch r.Method {
+	case http.MethodGet:
+		{
+			w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(job)
+		}
+	case http.MethodDelete:
+		{
+			// requests already added in the object pool by the job are not removed
+			if err := a.jobPool.RemoveJob(job.Id); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+				logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+				a.log.Error("unable to remove job", logExtraFields)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			a.log.Info("job removed", logExtraFields)
+		}
+	default:
+		{
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// example:
+// curl -X POST http://bot-host/jobs/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00/pause
+
+func (a *APIServiceT) postJobPause(w http.ResponseWriter, r *http.Request) {
+	a.setJobState(w, r, pools.JobStatePaused, pools.JobStateRunning)
+}
+
+// example:
+// curl -X POST http://bot-host/jobs/2b1f0c8e9a7d4c3e8f6a5b4c3d2e1f00/resume
+
+func (a *APIServiceT) postJobResume(w http.ResponseWriter, r *http.Request) {
+	// failed jobs are also resumed from their last checkpoint
+	a.setJobState(w, r, pools.JobStateRunning, pools.JobStatePaused, pools.JobStateFailed)
+}
+
+func (a *APIServiceT) setJobState(w http.ResponseWriter, r *http.Request, state string, fromStates ...string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	logExtraFields := global.GetLogExtraFieldsAPI()
+
+	id := r.PathValue("id")
+	if _, ok := a.jobPool.GetJob(id); !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	job, err := a.jobPool.SetJobState(id, state, "", fromStates...)
+	logExtraFields[global.LogFieldKeyExtraObject] = job.String()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		a.log.Error("unable to change job state", logExtraFields)
+		return
+	}
+
+	w.Header().Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+
+	a.log.Info(fmt.Sprintf("job moved to '%s' state", state), logExtraFields)
+}
+
+func (a *APIServiceT) validateJob(job pools.JobT) (err error) {
+	if job.Bucket == "" {
+		return fmt.Errorf("job bucket is empty")
+	}
+
+	found := slices.ContainsFunc(a.config.ObjectWorker.Sources, func(source v1alpha3.SourceConfigT) bool {
+		return source.Name == job.Source
+	})
+	if !found {
+		return fmt.Errorf("job source '%s' not found in configuration", job.Source)
+	}
+
+	return err
+}
@@ -0,0 +1,55 @@
+package apiService
+
+import (
+	"bufio"
+	"net/http"
+	"unicode"
+
+	"bot/internal/metrics"
+)
+
+type statusRecorderT struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorderT) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap returns the wrapped writer, used by the http.ResponseController
+// to reach the connection deadlines.
+func (r *statusRecorderT) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrument counts the requests handled in a route as accepted or rejected
+// depending on the response status code.
+func instrument(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorderT{ResponseWriter: w, statusCode: http.StatusOK}
+		handler(recorder, r)
+
+		result := metrics.ResultAccepted
+		if recorder.statusCode >= http.StatusBadRequest {
+			result = metrics.ResultRejected
+		}
+		metrics.APIRequests.WithLabelValues(route, result).Inc()
+	}
+}
+
+// peekFirstByte returns the first not blank byte in the reader without consuming it.
+func peekFirstByte(reader *bufio.Reader) (b byte, err error) {
+	for {
+		bytes, err := reader.Peek(1)
+		if err != nil {
+			return b, err
+		}
+
+		if !unicode.IsSpace(rune(bytes[0])) {
+			return bytes[0], err
+		}
+		reader.ReadByte()
+	}
+}
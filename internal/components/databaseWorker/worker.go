@@ -5,13 +5,16 @@ import (
 	"crypto/md5"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"bot/api/v1alpha3"
 	"bot/internal/global"
 	"bot/internal/logger"
 	"bot/internal/managers/database"
+	"bot/internal/metrics"
 	"bot/internal/pools"
+	"bot/internal/retry"
 )
 
 type DatabaseWorkerT struct {
@@ -19,13 +22,24 @@ type DatabaseWorkerT struct {
 	log    logger.LoggerT
 
 	databaseRequestPool *pools.DatabaseRequestPoolT
-	databaseManager     database.ManagerT
+	transferStatusPool  *pools.TransferStatusPoolT
+	deadLetterPool      *pools.DeadLetterPoolT
+	databaseManager     database.DatabaseManagerI
+	retryPolicy         *retry.PolicyT
+
+	// stopped ends the worker flow after the requests in process,
+	// flowWg waits for it in the shutdown
+	stopped atomic.Bool
+	flowWg  sync.WaitGroup
 }
 
-func NewDatabaseWorker(config *v1alpha3.BOTConfigT, dbPool *pools.DatabaseRequestPoolT) (dw *DatabaseWorkerT, err error) {
+func NewDatabaseWorker(config *v1alpha3.BOTConfigT, dbPool *pools.DatabaseRequestPoolT,
+	statusPool *pools.TransferStatusPoolT, deadLetterPool *pools.DeadLetterPoolT) (dw *DatabaseWorkerT, err error) {
 	dw = &DatabaseWorkerT{
 		config:              config,
 		databaseRequestPool: dbPool,
+		transferStatusPool:  statusPool,
+		deadLetterPool:      deadLetterPool,
 	}
 
 	logCommon := global.GetLogCommonFields()
@@ -36,30 +50,76 @@ func NewDatabaseWorker(config *v1alpha3.BOTConfigT, dbPool *pools.DatabaseReques
 		logCommon,
 	)
 
-	dw.databaseManager, err = database.NewManager(context.Background(),
+	dw.retryPolicy, err = retry.NewPolicy(dw.config.DatabaseWorker.Retry)
+	if err != nil {
+		return dw, err
+	}
+
+	dw.databaseManager, err = database.GetManager(context.Background(),
 		dw.config.DatabaseWorker.Database,
 	)
+	if err != nil {
+		return dw, err
+	}
+
+	err = dw.databaseManager.CheckSchema()
+	if err != nil {
+		err = fmt.Errorf("unable to check database table schema: %w", err)
+		return dw, err
+	}
 
 	return dw, err
 }
 
 func (d *DatabaseWorkerT) Run() {
 	global.ServerState.SetDatabaseReady()
+	d.flowWg.Add(1)
 	go d.flow()
 }
 
-func (d *DatabaseWorkerT) Shutdown() {
+// Shutdown stops the worker flow and flushes the requests in the pool once, also the ones
+// waiting for a retry backoff, until the flush timeout. It returns the requests left in the pool.
+func (dw *DatabaseWorkerT) Shutdown() (remaining int) {
+	logExtraFields := global.GetLogExtraFieldsDatabaseWorker()
+
+	dw.stopped.Store(true)
+	dw.flowWg.Wait()
+
+	requests := []pools.DatabaseRequestT{}
+	for _, request := range dw.databaseRequestPool.GetPool() {
+		requests = append(requests, request)
+	}
+
+	deadline := time.Now().Add(dw.config.Shutdown.FlushTimeout)
+	for start := 0; start < len(requests) && time.Now().Before(deadline); start += dw.config.DatabaseWorker.RequestsByChildThread {
+		end := min(start+dw.config.DatabaseWorker.RequestsByChildThread, len(requests))
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		dw.processRequestList(&wg, requests[start:end])
+	}
+
+	dw.databaseManager.Close()
+
+	remaining = len(dw.databaseRequestPool.GetPool())
+	logExtraFields[global.LogFieldKeyExtraCurrentPoolLength] = remaining
+	dw.log.Info("database worker stopped", logExtraFields)
+
+	return remaining
 }
 
 func (dw *DatabaseWorkerT) flow() {
+	defer dw.flowWg.Done()
+
 	logExtraFields := global.GetLogExtraFieldsDatabaseWorker()
 
 	emptyPoolLog := true
-	for {
+	for !dw.stopped.Load() {
 		// CONSUME REQUESTS TO MIGRATE FROM MAP OR WAIT
 		databaseRequestPool := dw.databaseRequestPool.GetPool()
 
 		poolLen := len(databaseRequestPool)
+		metrics.DatabasePoolLength.Set(float64(poolLen))
 		if poolLen == 0 {
 			if emptyPoolLog {
 				dw.log.Debug("database request pool empty", logExtraFields)
@@ -75,9 +135,14 @@ func (dw *DatabaseWorkerT) flow() {
 		currentThreads := 0
 		requestIndex := 0
 		requestsCount := 0
-		for key, request := range databaseRequestPool {
+		now := time.Now()
+		for _, request := range databaseRequestPool {
+			// requests waiting for a retry backoff are kept in the pool
+			if request.NotBefore.After(now) {
+				continue
+			}
+
 			requestList = append(requestList, request)
-			dw.databaseRequestPool.RemoveRequest(key)
 			requestsCount++
 
 			if requestIndex++; requestIndex >= dw.config.DatabaseWorker.RequestsByChildThread {
@@ -96,6 +161,11 @@ func (dw *DatabaseWorkerT) flow() {
 			currentThreads++
 		}
 
+		if requestsCount == 0 {
+			time.Sleep(2 * time.Second)
+			continue
+		}
+
 		wg := sync.WaitGroup{}
 		for _, requests := range threadList {
 			wg.Add(1)
@@ -108,7 +178,14 @@ func (dw *DatabaseWorkerT) flow() {
 		logExtraFields[global.LogFieldKeyExtraCurrentPoolLength] = poolLen - requestsCount
 		dw.log.Debug("database worker handle requests", logExtraFields)
 
+		metrics.DatabaseActiveRequests.Set(float64(requestsCount))
+		metrics.DatabaseActiveThreads.Set(float64(currentThreads))
+		metrics.DatabasePoolLength.Set(float64(poolLen - requestsCount))
+
 		wg.Wait()
+
+		metrics.DatabaseActiveRequests.Set(0)
+		metrics.DatabaseActiveThreads.Set(0)
 	}
 }
 
@@ -127,11 +204,84 @@ func (dw *DatabaseWorkerT) processRequestList(wg *sync.WaitGroup, requests []poo
 	logExtraFields[global.LogFieldKeyExtraRequestList] = reqsStr
 
 	dw.log.Info("process database request list", logExtraFields)
-	err := dw.databaseManager.InsertObjectListIfNotExist(dw.config.DatabaseWorker.Database.Table, requests)
+	insertStart := time.Now()
+	err := dw.databaseManager.InsertObjectList(requests)
+	metrics.DatabaseBatchSize.Observe(float64(len(requests)))
+	metrics.DatabaseInsertDuration.WithLabelValues(metrics.GetResult(err)).Observe(time.Since(insertStart).Seconds())
 	if err != nil {
 		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
 		dw.log.Error("unable to process database request list", logExtraFields)
 	} else {
 		dw.log.Info("success in process database request list", logExtraFields)
 	}
+
+	if err != nil {
+		dw.handleFailedRequestList(requests, err)
+		return
+	}
+
+	for _, req := range requests {
+		dw.transferStatusPool.SetState(req.TransferId, pools.TransferStateRecorded, "")
+	}
+
+	// the requests are removed from the pool only when they are processed,
+	// so a persisted pool can replay them if the process dies in the middle
+	err = dw.databaseRequestPool.RemoveRequests(requests)
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		dw.log.Error("unable to remove database request list from pool", logExtraFields)
+	}
+}
+
+// handleFailedRequestList keeps in the pool the requests with attempts left,
+// waiting for the next backoff, and moves the rest to the dead letter pool.
+func (dw *DatabaseWorkerT) handleFailedRequestList(requests []pools.DatabaseRequestT, reqErr error) {
+	logExtraFields := global.GetLogExtraFieldsDatabaseWorker()
+
+	for _, req := range requests {
+		logExtraFields[global.LogFieldKeyExtraRequestList] = req.String()
+		logExtraFields[global.LogFieldKeyExtraError] = reqErr.Error()
+
+		req.Attempts++
+		logExtraFields[global.LogFieldKeyExtraAttempts] = req.Attempts
+		if dw.retryPolicy.ShouldRetry(req.Attempts, reqErr) {
+			req.NotBefore = time.Now().Add(dw.retryPolicy.Backoff(req.Attempts))
+			dw.transferStatusPool.SetState(req.TransferId, pools.TransferStateRetrying, reqErr.Error())
+
+			// a newer request for the object added while processing it takes its place
+			replaced, err := dw.databaseRequestPool.ReplaceRequest(req)
+			if err != nil {
+				logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+				dw.log.Error("unable to schedule database request retry in pool", logExtraFields)
+			}
+			if !replaced && err == nil {
+				dw.transferStatusPool.SetState(req.TransferId, pools.TransferStateSkipped, "superseded by a newer database request")
+				dw.log.Info("database request retry superseded by a newer request", logExtraFields)
+			}
+			continue
+		}
+
+		dw.transferStatusPool.SetState(req.TransferId, pools.TransferStateFailed, reqErr.Error())
+
+		err := dw.deadLetterPool.AddLetter(pools.DeadLetterT{
+			Id:              req.TransferId,
+			Kind:            pools.DeadLetterKindDatabase,
+			Error:           reqErr.Error(),
+			Attempts:        req.Attempts,
+			FailedAt:        time.Now(),
+			DatabaseRequest: &req,
+		})
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			dw.log.Error("unable to add database request in dead letter pool", logExtraFields)
+		} else {
+			dw.log.Error("database request moved to dead letter pool", logExtraFields)
+		}
+
+		err = dw.databaseRequestPool.RemoveRequests([]pools.DatabaseRequestT{req})
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			dw.log.Error("unable to remove database request from pool", logExtraFields)
+		}
+	}
 }
@@ -0,0 +1,100 @@
+package hashringWorker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"bot/internal/global"
+	"bot/internal/logger"
+	"bot/internal/managers/gossip"
+	"bot/internal/pools"
+)
+
+// gossipLogWriterT writes the memberlist log lines in the hashring worker logger.
+type gossipLogWriterT struct {
+	log logger.LoggerT
+}
+
+func (w *gossipLogWriterT) Write(p []byte) (n int, err error) {
+	w.log.Debug(strings.TrimSpace(string(p)), global.GetLogExtraFieldsHashringWorker())
+	return len(p), err
+}
+
+// joinGossip contacts the gossip seeds until any of them responds or the self check timeout expires.
+// The instance starts alone when none responds, as the other instances can join it later.
+func (hw *HashringWorkerT) joinGossip() {
+	logExtraFields := global.GetLogExtraFieldsHashringWorker()
+
+	deadline := time.Now().Add(hw.config.HashRingWorker.SelfCheckTimeout)
+	for {
+		contacted, err := hw.gossip.Join()
+		if err == nil {
+			hw.log.Info(fmt.Sprintf("joined gossip cluster contacting %d seeds", contacted), logExtraFields)
+			return
+		}
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		if time.Now().After(deadline) {
+			hw.log.Warn("unable to join gossip cluster, starting alone", logExtraFields)
+			return
+		}
+
+		hw.log.Debug("unable to join gossip cluster", logExtraFields)
+		time.Sleep(4 * time.Second)
+	}
+}
+
+// gossipFlow updates the servers pool and the hashring with the gossip membership events,
+// and joins the cluster again when this instance is left alone.
+func (hw *HashringWorkerT) gossipFlow() {
+	defer hw.flowWg.Done()
+
+	logExtraFields := global.GetLogExtraFieldsHashringWorker()
+
+	ticker := time.NewTicker(hw.config.HashRingWorker.Discovery.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hw.stop:
+			{
+				return
+			}
+		case <-ticker.C:
+			{
+				if !hw.gossip.IsAlone() {
+					continue
+				}
+
+				if _, err := hw.gossip.Join(); err != nil {
+					logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+					hw.log.Debug("unable to join gossip cluster", logExtraFields)
+				}
+			}
+		case event := <-hw.gossip.Events():
+			{
+				if event.Server.Name == hw.config.Name {
+					continue
+				}
+
+				hw.log.Debug(fmt.Sprintf("gossip member %s event for instance '%s'", event.Type, event.Server.String()), logExtraFields)
+
+				// the updated members are replaced, as their address may change
+				added := []pools.ServerT{}
+				removed := []pools.ServerT{}
+				if stored, ok := hw.serverInstancePool.GetServerByName(event.Server.Name); ok {
+					if stored == event.Server && event.Type != gossip.EventLeave {
+						continue
+					}
+					removed = append(removed, stored)
+				}
+				if event.Type != gossip.EventLeave {
+					added = append(added, event.Server)
+				}
+
+				hw.updateServers(added, removed)
+			}
+		}
+	}
+}
@@ -1,29 +1,35 @@
 package hashringWorker
 
 import (
-	"net"
-	"slices"
+	"fmt"
 	"time"
+
+	"bot/internal/global"
 )
 
-func (hw *HashringWorkerT) CheckOwnHost() {
+// CheckOwnHost waits until the advertised address of this instance is in the
+// discovered instances, returning an error when it is not found before the timeout.
+func (hw *HashringWorkerT) CheckOwnHost() (err error) {
+	logExtraFields := global.GetLogExtraFieldsHashringWorker()
 
-	found := false
-	for !found {
-		time.Sleep(4 * time.Second)
+	deadline := time.Now().Add(hw.config.HashRingWorker.SelfCheckTimeout)
+	for {
+		found, discoveryErr := hw.discovery.HasAddress(hw.config.HashRingWorker.AdvertisedAddress)
+		if found {
+			return err
+		}
 
-		discoveredHosts, err := net.LookupHost(hw.config.HashRingWorker.Proxy)
-		if err != nil {
-			// logger.Logger.Errorf("unable to look up in '%s' proxy host: %s", global.Config.HashRingWorker.Proxy, err.Error())
-			continue
+		if discoveryErr != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = discoveryErr.Error()
+			hw.log.Debug(fmt.Sprintf("unable to discover instances with '%s' discovery", hw.config.HashRingWorker.Discovery.Type), logExtraFields)
 		}
 
-		// TODO
-		if slices.Contains(discoveredHosts, "global.Config.APIService.Address") {
-			found = true
-			continue
+		if time.Now().After(deadline) {
+			err = fmt.Errorf("unable to find '%s' own host in '%s' discovered instances after %s",
+				hw.config.HashRingWorker.AdvertisedAddress, hw.config.HashRingWorker.Discovery.Type, hw.config.HashRingWorker.SelfCheckTimeout)
+			return err
 		}
 
-		// logger.Logger.Errorf("unable to find '%s' own host in '%s' proxy host resolution", global.Config.APIService.Address, global.Config.HashRingWorker.Proxy)
+		time.Sleep(4 * time.Second)
 	}
 }
@@ -1,70 +1,159 @@
 package hashringWorker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"slices"
+	"sync"
 	"time"
 
 	"bot/api/v1alpha3"
 	"bot/internal/global"
 	"bot/internal/logger"
+	"bot/internal/managers/discovery"
+	"bot/internal/managers/gossip"
 	"bot/internal/managers/hashring"
+	"bot/internal/metrics"
 	"bot/internal/pools"
 )
 
 type HashringWorkerT struct {
+	ctx    context.Context
 	config *v1alpha3.BOTConfigT
 	log    logger.LoggerT
 
 	hashring           *hashring.HashRingT
 	serverInstancePool *pools.ServerInstancesPoolT
+	discovery          discovery.DiscoveryManagerI
+	gossip             *gossip.GossipManagerT
+	httpClient         *http.Client
+
+	// stop is closed to stop the hashring updates, with flowWg waiting for the update flow
+	stop     chan struct{}
+	stopOnce sync.Once
+	flowWg   sync.WaitGroup
 }
 
-func NewHashringWorker(config *v1alpha3.BOTConfigT, serverPool *pools.ServerInstancesPoolT) (hw *HashringWorkerT) {
+func NewHashringWorker(config *v1alpha3.BOTConfigT, serverPool *pools.ServerInstancesPoolT, hashring *hashring.HashRingT) (hw *HashringWorkerT, err error) {
 	hw = &HashringWorkerT{
+		ctx:                context.Background(),
 		config:             config,
+		hashring:           hashring,
 		serverInstancePool: serverPool,
+		stop:               make(chan struct{}),
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
 	}
 
-	return hw
+	logCommon := global.GetLogCommonFields()
+	logCommon[global.LogFieldKeyCommonInstance] = hw.config.Name
+	logCommon[global.LogFieldKeyCommonComponent] = global.LogFieldValueComponentHashringWorker
+	hw.log = logger.NewLogger(context.Background(),
+		logger.GetLevel(hw.config.HashRingWorker.LogLevel),
+		logCommon,
+	)
+
+	if !hw.config.HashRingWorker.Enabled {
+		return hw, err
+	}
+
+	// the gossip membership replaces the discovery and the instances info requests
+	if hw.config.HashRingWorker.Discovery.Type == discovery.TypeGossip {
+		hw.gossip = &gossip.GossipManagerT{}
+		err = hw.gossip.Init(hw.ctx, hw.config.HashRingWorker, pools.ServerT{
+			Name:    hw.config.Name,
+			Address: hw.config.HashRingWorker.AdvertisedAddress,
+			Port:    hw.config.HashRingWorker.AdvertisedPort,
+			Version: global.Version,
+			Weight:  hw.config.HashRingWorker.Weight,
+		}, &gossipLogWriterT{log: hw.log})
+		return hw, err
+	}
+
+	hw.discovery, err = discovery.GetManager(hw.ctx, hw.config.HashRingWorker)
+	if err != nil {
+		return hw, err
+	}
+
+	return hw, err
 }
 
 func (hw *HashringWorkerT) Run() {
 	if hw.config.HashRingWorker.Enabled {
-		// check host is added in load balancer
-		hw.CheckOwnHost()
-		// TODO: add config to get api address
-		// hw.log.Info("found '%s' own host in '%s' proxy host resolution", "global.Config.APIService.Address", hw.config.HashRingWorker.Proxy)
+		if hw.gossip != nil {
+			hw.joinGossip()
 
-		hw.hashring = hashring.NewHashRing(hw.config.HashRingWorker.VNodes)
+			hw.hashring.AddNodes([]hashring.NodeT{{Name: hw.config.Name, Weight: hw.config.HashRingWorker.Weight}})
+			metrics.HashringMembers.Set(1)
 
-		hw.hashring.AddNodes([]string{"global.Config.Name"})
+			hw.flowWg.Add(1)
+			go hw.gossipFlow()
+			global.ServerState.SetHashringReady()
+			return
+		}
+
+		logExtraFields := global.GetLogExtraFieldsHashringWorker()
+
+		// check host is added in load balancer, the other instances
+		// can still discover it later when it is not found yet
+		err := hw.CheckOwnHost()
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			hw.log.Warn("unable to check own host in discovered instances", logExtraFields)
+		} else {
+			hw.log.Info(fmt.Sprintf("found '%s' own host in '%s' discovered instances",
+				hw.config.HashRingWorker.AdvertisedAddress, hw.config.HashRingWorker.Discovery.Type), logExtraFields)
+		}
 
-		global.ServerState.SetHashringReady()
+		hw.hashring.AddNodes([]hashring.NodeT{{Name: hw.config.Name, Weight: hw.config.HashRingWorker.Weight}})
+		metrics.HashringMembers.Set(1)
 
+		hw.flowWg.Add(1)
 		go hw.flow()
 	}
 
 	global.ServerState.SetHashringReady()
 }
 
+// StopUpdates stops the hashring and servers pool updates, waiting for the update in progress.
+// The hashring is not changed anymore by the discovery or the gossip membership events.
+func (hw *HashringWorkerT) StopUpdates() {
+	hw.stopOnce.Do(func() {
+		close(hw.stop)
+	})
+	hw.flowWg.Wait()
+}
+
 func (hw *HashringWorkerT) Shutdown() {
+	hw.StopUpdates()
+
+	if hw.discovery != nil {
+		hw.discovery.Close()
+	}
 
+	if hw.gossip != nil {
+		err := hw.gossip.Close()
+		if err != nil {
+			logExtraFields := global.GetLogExtraFieldsHashringWorker()
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			hw.log.Error("unable to leave gossip cluster", logExtraFields)
+		}
+	}
 }
 
 func (hw *HashringWorkerT) discoverServerAddresses() (instancesAddrs []string, err error) {
-	discoveredHosts, err := net.LookupHost(hw.config.HashRingWorker.Proxy)
+	discoveredHosts, err := hw.discovery.GetAddresses()
 	if err != nil {
 		return instancesAddrs, err
 	}
 
 	for _, dHost := range discoveredHosts {
-		// TODO: add config to get api
-		if dHost != "global.Config.APIService.Address" {
+		if dHost != hw.config.HashRingWorker.AdvertisedAddress {
 			instancesAddrs = append(instancesAddrs, dHost)
 		}
 	}
@@ -72,19 +161,19 @@ func (hw *HashringWorkerT) discoverServerAddresses() (instancesAddrs []string, e
 	return instancesAddrs, err
 }
 
-func (h *HashringWorkerT) checkHealth(address string) (err error) {
-	// TODO: add config to get api
-	requestURL := fmt.Sprintf("http://%s:%s%s",
-		address,
-		"global.Config.APIService.Port",
+func (hw *HashringWorkerT) checkHealth(address string) (err error) {
+	// the instances behind the proxy listen in the same port
+	requestURL := fmt.Sprintf("http://%s%s",
+		net.JoinHostPort(address, hw.config.HashRingWorker.AdvertisedPort),
 		global.EndpointHealthz,
 	)
-	res, err := http.Get(requestURL)
+	res, err := hw.httpClient.Get(requestURL)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 
-	if res != nil && res.StatusCode != http.StatusOK {
+	if res.StatusCode != http.StatusOK {
 		err = fmt.Errorf("ready endpoint return not OK status")
 	}
 
@@ -92,27 +181,31 @@ func (h *HashringWorkerT) checkHealth(address string) (err error) {
 }
 
 func (hw *HashringWorkerT) getServersInfo(addrsAdded []string) (result []pools.ServerT) {
+	logExtraFields := global.GetLogExtraFieldsHashringWorker()
+
 	for _, address := range addrsAdded {
 		err := hw.checkHealth(address)
 		if err != nil {
-			// hw.log.Error("error checking api of instance with address '%s': %s", address, err.Error())
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			hw.log.Error(fmt.Sprintf("error checking api of instance with address '%s'", address), logExtraFields)
 			continue
 		}
 
-		requestURL := fmt.Sprintf("http://%s:%s%s",
-			address,
-			"global.Config.APIService.Port",
+		requestURL := fmt.Sprintf("http://%s%s",
+			net.JoinHostPort(address, hw.config.HashRingWorker.AdvertisedPort),
 			global.EndpointInfo,
 		)
-		res, err := http.Get(requestURL)
+		res, err := hw.httpClient.Get(requestURL)
 		if err != nil {
-			// logger.Logger.Errorf("error getting info of instance with address '%s': %s", address, err.Error())
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			hw.log.Error(fmt.Sprintf("error getting info of instance with address '%s'", address), logExtraFields)
 			continue
 		}
 
 		resBodyBytes, err := io.ReadAll(res.Body)
 		if err != nil {
-			// logger.Logger.Errorf("error reading info request body of instance with address '%s': %s", address, err.Error())
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			hw.log.Error(fmt.Sprintf("error reading info request body of instance with address '%s'", address), logExtraFields)
 			res.Body.Close()
 			continue
 		}
@@ -121,10 +214,14 @@ func (hw *HashringWorkerT) getServersInfo(addrsAdded []string) (result []pools.S
 		server := pools.ServerT{}
 		err = json.Unmarshal(resBodyBytes, &server)
 		if err != nil {
-			// logger.Logger.Errorf("error parsing info request body of instance with address '%s': %s", address, err.Error())
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			hw.log.Error(fmt.Sprintf("error parsing info request body of instance with address '%s'", address), logExtraFields)
 			continue
 		}
 
+		// the pool stores the servers by the discovered address
+		server.Address = address
+
 		result = append(result, server)
 	}
 
@@ -134,8 +231,10 @@ func (hw *HashringWorkerT) getServersInfo(addrsAdded []string) (result []pools.S
 func (hw *HashringWorkerT) getServersPoolChanges(currentServersAddrsList []string) (added []pools.ServerT, removed []pools.ServerT) {
 	storedPool := hw.serverInstancePool.GetPool()
 
+	// the discovered servers failing the health check are removed too,
+	// as not every discovery drops the unhealthy instances
 	for _, server := range storedPool {
-		if !slices.Contains(currentServersAddrsList, server.Address) {
+		if !slices.Contains(currentServersAddrsList, server.Address) || hw.checkHealth(server.Address) != nil {
 			removed = append(removed, server)
 		}
 	}
@@ -153,41 +252,70 @@ func (hw *HashringWorkerT) getServersPoolChanges(currentServersAddrsList []strin
 }
 
 func (hw *HashringWorkerT) flow() {
+	defer hw.flowWg.Done()
+
+	logExtraFields := global.GetLogExtraFieldsHashringWorker()
+
+	// the discovery events trigger the updates as soon as the instances change,
+	// the polling interval covers the discoveries without events and the failed health checks
+	ticker := time.NewTicker(hw.config.HashRingWorker.Discovery.Interval)
+	defer ticker.Stop()
+
 	for {
-		time.Sleep(2 * time.Second)
+		select {
+		case <-ticker.C:
+		case <-hw.discovery.Events():
+		case <-hw.stop:
+			return
+		}
 
 		currentServersAddrsList, err := hw.discoverServerAddresses()
 		if err != nil {
-			// logger.Logger.Errorf("unable to discover current servers in '%s' proxy host: %s", global.Config.HashRingWorker.Proxy, err.Error())
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			hw.log.Error(fmt.Sprintf("unable to discover current servers with '%s' discovery", hw.config.HashRingWorker.Discovery.Type), logExtraFields)
+			continue
 		}
+		logExtraFields[global.LogFieldKeyExtraError] = global.LogFieldValueDefault
 
 		serversAdded, serversRemoved := hw.getServersPoolChanges(currentServersAddrsList)
+		hw.updateServers(serversAdded, serversRemoved)
+	}
+}
 
-		if len(serversAdded) != 0 || len(serversRemoved) != 0 {
-			// logger.Logger.Infof("update servers pool and compute hashring")
+// updateServers applies the added and removed servers to the servers pool and the hashring.
+func (hw *HashringWorkerT) updateServers(serversAdded []pools.ServerT, serversRemoved []pools.ServerT) {
+	if len(serversAdded) == 0 && len(serversRemoved) == 0 {
+		return
+	}
 
-			hw.serverInstancePool.AddServers(serversAdded)
-			hw.serverInstancePool.RemoveServers(serversRemoved)
+	logExtraFields := global.GetLogExtraFieldsHashringWorker()
+	hw.log.Info("update servers pool and compute hashring", logExtraFields)
 
-			serversList := hw.serverInstancePool.GetServersList()
-			_ = serversList
-			// logger.Logger.Infof("current servers in hashring: %v", serversList)
+	hw.serverInstancePool.RemoveServers(serversRemoved)
+	hw.serverInstancePool.AddServers(serversAdded)
 
-			// GENERATE HASH RING
+	serversNames := []string{}
+	for _, server := range hw.serverInstancePool.GetServersList() {
+		serversNames = append(serversNames, server.Name)
+	}
+	hw.log.Info(fmt.Sprintf("current servers in hashring: %v", serversNames), logExtraFields)
 
-			added := []string{}
-			removed := []string{}
+	// GENERATE HASH RING
 
-			for _, server := range serversAdded {
-				added = append(added, server.Name)
-			}
+	added := []hashring.NodeT{}
+	removed := []string{}
 
-			for _, server := range serversRemoved {
-				removed = append(removed, server.Name)
-			}
+	for _, server := range serversAdded {
+		added = append(added, hashring.NodeT{Name: server.Name, Weight: server.Weight})
+	}
 
-			hw.hashring.RemoveNodes(removed)
-			hw.hashring.AddNodes(added)
-		}
+	for _, server := range serversRemoved {
+		removed = append(removed, server.Name)
 	}
+
+	hw.hashring.RemoveNodes(removed)
+	hw.hashring.AddNodes(added)
+
+	metrics.HashringRebalances.Inc()
+	metrics.HashringMembers.Set(float64(len(hw.serverInstancePool.GetPool()) + 1))
 }
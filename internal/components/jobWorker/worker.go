@@ -0,0 +1,199 @@
+package jobWorker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bot/api/v1alpha3"
+	"bot/internal/global"
+	"bot/internal/logger"
+	"bot/internal/managers/objectStorage"
+	"bot/internal/managers/routing"
+	"bot/internal/pools"
+)
+
+type JobWorkerT struct {
+	ctx    context.Context
+	config *v1alpha3.BOTConfigT
+	log    logger.LoggerT
+
+	jobPool            *pools.JobPoolT
+	objectRequestPool  *pools.ObjectRequestPoolT
+	transferStatusPool *pools.TransferStatusPoolT
+
+	router  *routing.RouterT
+	sources map[string]objectStorage.ObjectManagerI
+
+	// stopped ends the worker flow after the page in process,
+	// flowWg waits for it in the shutdown
+	stopped atomic.Bool
+	flowWg  sync.WaitGroup
+}
+
+func NewJobWorker(config *v1alpha3.BOTConfigT, jobPool *pools.JobPoolT, objectPool *pools.ObjectRequestPoolT,
+	statusPool *pools.TransferStatusPoolT) (jw *JobWorkerT, err error) {
+	jw = &JobWorkerT{
+		ctx:                context.Background(),
+		config:             config,
+		jobPool:            jobPool,
+		objectRequestPool:  objectPool,
+		transferStatusPool: statusPool,
+	}
+
+	logCommon := global.GetLogCommonFields()
+	logCommon[global.LogFieldKeyCommonInstance] = jw.config.Name
+	logCommon[global.LogFieldKeyCommonComponent] = global.LogFieldValueComponentJobWorker
+	jw.log = logger.NewLogger(context.Background(),
+		logger.GetLevel(jw.config.JobWorker.LogLevel),
+		logCommon,
+	)
+
+	jw.router = routing.NewRouter(&jw.config.ObjectWorker)
+
+	jw.sources = map[string]objectStorage.ObjectManagerI{}
+	for _, sv := range config.ObjectWorker.Sources {
+		jw.sources[sv.Name], err = objectStorage.GetManager(jw.ctx, sv)
+		if err != nil {
+			return jw, err
+		}
+	}
+
+	return jw, err
+}
+
+func (jw *JobWorkerT) Run() {
+	global.ServerState.SetJobReady()
+	jw.flowWg.Add(1)
+	go jw.flow()
+}
+
+// Shutdown stops listing the job pages, the running jobs continue
+// from their checkpoint when the pool is persisted.
+func (jw *JobWorkerT) Shutdown() {
+	jw.stopped.Store(true)
+	jw.flowWg.Wait()
+}
+
+func (jw *JobWorkerT) flow() {
+	defer jw.flowWg.Done()
+
+	for !jw.stopped.Load() {
+		time.Sleep(2 * time.Second)
+
+		for _, job := range jw.jobPool.GetPool() {
+			if jw.stopped.Load() {
+				break
+			}
+
+			if job.State != pools.JobStateRunning {
+				continue
+			}
+
+			// jobs wait for the object worker to consume the pool
+			if len(jw.objectRequestPool.GetPool()) >= jw.config.JobWorker.MaxPoolLength {
+				break
+			}
+
+			jw.processJobPage(job)
+		}
+	}
+}
+
+// processJobPage lists the next page of objects from the job checkpoint and adds them
+// in the object request pool. The checkpoint only moves when the whole page is in the pool.
+func (jw *JobWorkerT) processJobPage(job pools.JobT) {
+	logExtraFields := global.GetLogExtraFieldsJobWorker()
+	logExtraFields[global.LogFieldKeyExtraJob] = job.String()
+
+	objs, listMarker, lastPage, err := jw.listJobPage(job)
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		jw.log.Error("unable to list job objects", logExtraFields)
+
+		_, err = jw.jobPool.SetJobState(job.Id, pools.JobStateFailed, err.Error(), pools.JobStateRunning)
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			jw.log.Error("unable to update job state", logExtraFields)
+		}
+		return
+	}
+
+	enqueued := int64(0)
+	rejected := int64(0)
+	for _, backend := range objs {
+		// the listed backend objects are requested with the path routed to them
+		obj, source, routeErr := jw.router.GetRequestObject(objectStorage.ObjectT{
+			Bucket:   job.RequestBucket,
+			Metadata: job.Metadata.Clone(),
+		}, backend)
+		if routeErr == nil && source != job.Source {
+			routeErr = fmt.Errorf("backend object routed from source '%s'", source)
+		}
+		if routeErr == nil {
+			_, _, routeErr = jw.router.GetFrontendObject(obj)
+		}
+		if routeErr != nil {
+			rejected++
+			continue
+		}
+
+		if _, err = jw.objectRequestPool.AddTrackedRequest(jw.transferStatusPool, pools.ObjectRequestT{Object: obj}); err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			jw.log.Error("unable to add job object request in pool", logExtraFields)
+			return
+		}
+		enqueued++
+	}
+
+	checkpoint := job.Checkpoint
+	if len(objs) > 0 {
+		checkpoint = objs[len(objs)-1].Path
+	}
+
+	job, err = jw.jobPool.AddJobProgress(job.Id, checkpoint, listMarker, int64(len(objs)), enqueued, rejected)
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		jw.log.Error("unable to update job progress", logExtraFields)
+		return
+	}
+
+	jw.log.Debug(fmt.Sprintf("job page processed with %d listed, %d enqueued and %d rejected objects",
+		len(objs), enqueued, rejected), logExtraFields)
+
+	if lastPage {
+		_, err = jw.jobPool.SetJobState(job.Id, pools.JobStateCompleted, "", pools.JobStateRunning)
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			jw.log.Error("unable to update job state", logExtraFields)
+			return
+		}
+
+		jw.log.Info(fmt.Sprintf("job completed with %d listed, %d enqueued and %d rejected objects",
+			job.Listed, job.Enqueued, job.Rejected), logExtraFields)
+	}
+}
+
+// listJobPage lists the next page of objects of the job, from the backend marker in the sources
+// listing from markers, or from the checkpoint in the rest of them, and returns the marker
+// of the next page and if it is the last one.
+func (jw *JobWorkerT) listJobPage(job pools.JobT) (objs []objectStorage.ObjectT, listMarker string, lastPage bool, err error) {
+	// the persisted jobs can name a source removed from the config after they were created
+	source, ok := jw.sources[job.Source]
+	if !ok {
+		err = fmt.Errorf("job source '%s' not found in config", job.Source)
+		return objs, listMarker, lastPage, err
+	}
+
+	// the jobs with a checkpoint but without marker, persisted before the sources listed
+	// from markers, continue from the checkpoint
+	if lister, ok := source.(objectStorage.MarkerListerI); ok && (job.Checkpoint == "" || job.ListMarker != "") {
+		objs, listMarker, err = lister.ListPage(job.Bucket, job.Prefix, job.ListMarker, jw.config.JobWorker.PageSize)
+		return objs, listMarker, listMarker == "", err
+	}
+
+	objs, err = source.List(job.Bucket, job.Prefix, job.Checkpoint, jw.config.JobWorker.PageSize)
+	return objs, listMarker, len(objs) < jw.config.JobWorker.PageSize, err
+}
@@ -0,0 +1,118 @@
+package objectWorker
+
+import (
+	"fmt"
+	"time"
+
+	"bot/internal/global"
+	"bot/internal/managers/objectStorage"
+	"bot/internal/metrics"
+	"bot/internal/pools"
+)
+
+// getServerCopier returns the front source manager when it can copy the objects of the backend
+// source inside their shared backend, so the content is not streamed through the instance.
+func (ow *ObjectWorkerT) getServerCopier(backSource string, frontSource string) (copier objectStorage.ServerCopierI, ok bool) {
+	copier, ok = ow.sources[frontSource].(objectStorage.ServerCopierI)
+	if !ok {
+		return copier, ok
+	}
+
+	return copier, copier.CanCopyFrom(ow.sources[backSource])
+}
+
+// processServerCopy copies the requested object inside the backend shared by the front and backend
+// sources. The object attributes recorded and verified are the ones reported by the sources,
+// as its content is not read.
+func (ow *ObjectWorkerT) processServerCopy(request pools.ObjectRequestT, copier objectStorage.ServerCopierI,
+	back objectStorage.ObjectT, backSource string,
+	front objectStorage.ObjectT, frontSource string) (retryable bool, err error) {
+	logExtraFields := global.GetLogExtraFieldsObjectWorker()
+	logExtraFields[global.LogFieldKeyExtraTransferId] = request.Id
+	logExtraFields[global.LogFieldKeyExtraObject] = front.String()
+	logExtraFields[global.LogFieldKeyExtraBackendObject] = back.String()
+
+	ow.transferStatusPool.SetState(request.Id, pools.TransferStateCopying, "server-side copy")
+	backInfo, err := ow.sources[backSource].StatObject(back)
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to get backend object attributes", logExtraFields)
+		return true, err
+	}
+
+	transferStart := time.Now()
+	err = copier.CopyObject(back, front)
+
+	result := metrics.GetResult(err)
+	metrics.ObjectServerCopyBytes.WithLabelValues(backSource, frontSource, result).Add(float64(backInfo.Size))
+	metrics.ObjectTransferDuration.WithLabelValues(backSource, frontSource, result).
+		Observe(time.Since(transferStart).Seconds())
+
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to copy object in backend", logExtraFields)
+		return true, err
+	}
+
+	if ow.config.ObjectWorker.Verification.Enabled {
+		err = ow.verifyServerCopy(backInfo, front, frontSource)
+		if err != nil {
+			metrics.ObjectVerificationFailures.WithLabelValues(backSource, frontSource).Inc()
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			ow.log.Error("unable to verify object transfer integrity", logExtraFields)
+			return true, err
+		}
+	}
+
+	checksumAlgorithm, checksum := backInfo.GetSourceChecksum()
+
+	err = ow.databaseRequestPool.AddRequest(pools.DatabaseRequestT{
+		TransferId: request.Id,
+		BucketName: front.Bucket,
+		ObjectPath: front.Path,
+		MD5:        backInfo.MD5,
+
+		ChecksumAlgorithm: checksumAlgorithm,
+		Checksum:          checksum,
+		Size:              backInfo.Size,
+		ContentType:       backInfo.ContentType,
+		Source:            backSource,
+		TransferredAt:     time.Now(),
+		Instance:          ow.config.Name,
+	})
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to add database request in pool", logExtraFields)
+		return true, err
+	}
+
+	ow.transferStatusPool.SetState(request.Id, pools.TransferStateCopied, "")
+	ow.log.Info("success in process object transfer request with server-side copy", logExtraFields)
+
+	return false, err
+}
+
+// verifyServerCopy compares the size and the checksums of the frontend object with the ones
+// of the backend object. The checksums not available in both objects are not compared.
+func (ow *ObjectWorkerT) verifyServerCopy(backInfo objectStorage.ObjectInfoT,
+	front objectStorage.ObjectT, frontSource string) (err error) {
+	frontInfo, err := ow.sources[frontSource].StatObject(front)
+	if err != nil {
+		return err
+	}
+
+	if backInfo.Size != frontInfo.Size {
+		err = fmt.Errorf("integrity mismatch in frontend object size (expected: %d, stored: %d)", backInfo.Size, frontInfo.Size)
+		return err
+	}
+
+	for _, algorithm := range []string{objectStorage.ChecksumAlgorithmMD5, objectStorage.ChecksumAlgorithmSHA256, objectStorage.ChecksumAlgorithmCRC32C} {
+		expected, stored := backInfo.GetChecksum(algorithm), frontInfo.GetChecksum(algorithm)
+		if expected != "" && stored != "" && expected != stored {
+			err = fmt.Errorf("integrity mismatch in frontend object %s (expected: %s, stored: %s)", algorithm, expected, stored)
+			return err
+		}
+	}
+
+	return err
+}
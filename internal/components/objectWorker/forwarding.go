@@ -0,0 +1,145 @@
+package objectWorker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"bot/internal/global"
+	"bot/internal/managers/objectStorage"
+	"bot/internal/metrics"
+	"bot/internal/pools"
+)
+
+// forwardRequest sends the request to the hashring instance owning the object and returns
+// if it was forwarded. The requests are processed locally when this instance is the owner,
+// when they were forwarded by other instance or when the owner is unreachable.
+func (ow *ObjectWorkerT) forwardRequest(request pools.ObjectRequestT) (forwarded bool) {
+	if !ow.config.HashRingWorker.Enabled || request.Forwarded {
+		return forwarded
+	}
+
+	node := ow.hashring.GetNode(request.Object.Path)
+	if node == "" || node == ow.config.Name {
+		return forwarded
+	}
+
+	logExtraFields := global.GetLogExtraFieldsObjectWorker()
+	logExtraFields[global.LogFieldKeyExtraTransferId] = request.Id
+	logExtraFields[global.LogFieldKeyExtraObject] = request.Object.String()
+
+	server, ok := ow.serverInstancePool.GetServerByName(node)
+	if !ok {
+		logExtraFields[global.LogFieldKeyExtraError] = fmt.Sprintf("instance '%s' not found in server pool", node)
+		ow.log.Warn("unable to forward object transfer request, processing it locally", logExtraFields)
+		return forwarded
+	}
+
+	id, err := ow.sendTransferRequest(server, request.Object)
+	metrics.ObjectForwardedRequests.WithLabelValues(metrics.GetResult(err)).Inc()
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Warn("unable to forward object transfer request, processing it locally", logExtraFields)
+		return forwarded
+	}
+
+	ow.transferStatusPool.SetState(request.Id, pools.TransferStateForwarded,
+		fmt.Sprintf("forwarded to instance '%s' with transfer id '%s'", node, id))
+	ow.log.Info(fmt.Sprintf("object transfer request forwarded to instance '%s'", server.String()), logExtraFields)
+
+	return true
+}
+
+// handOffRequests removes this instance from the hashring and forwards the requests in the pool
+// to their new owners, returning the number of requests handed off. The requests are kept in the
+// pool when there is no other instance or it is unreachable.
+func (ow *ObjectWorkerT) handOffRequests() (handedOff int) {
+	ow.hashring.RemoveNodes([]string{ow.config.Name})
+
+	for _, request := range ow.objectRequestPool.GetPool() {
+		logExtraFields := global.GetLogExtraFieldsObjectWorker()
+		logExtraFields[global.LogFieldKeyExtraTransferId] = request.Id
+		logExtraFields[global.LogFieldKeyExtraObject] = request.Object.String()
+
+		node := ow.hashring.GetNode(request.Object.Path)
+		if node == "" {
+			logExtraFields[global.LogFieldKeyExtraError] = "no other instance in hashring"
+			ow.log.Warn("unable to hand off object transfer request, keeping it in pool", logExtraFields)
+			continue
+		}
+
+		server, ok := ow.serverInstancePool.GetServerByName(node)
+		if !ok {
+			logExtraFields[global.LogFieldKeyExtraError] = fmt.Sprintf("instance '%s' not found in server pool", node)
+			ow.log.Warn("unable to hand off object transfer request, keeping it in pool", logExtraFields)
+			continue
+		}
+
+		id, err := ow.sendTransferRequest(server, request.Object)
+		metrics.ObjectForwardedRequests.WithLabelValues(metrics.GetResult(err)).Inc()
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			ow.log.Warn("unable to hand off object transfer request, keeping it in pool", logExtraFields)
+			continue
+		}
+
+		ow.transferStatusPool.SetState(request.Id, pools.TransferStateForwarded,
+			fmt.Sprintf("handed off to instance '%s' with transfer id '%s' on shutdown", node, id))
+
+		_, err = ow.objectRequestPool.RemoveProcessedRequest(request)
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			ow.log.Error("unable to remove object request from pool", logExtraFields)
+		}
+
+		handedOff++
+		ow.log.Info(fmt.Sprintf("object transfer request handed off to instance '%s'", server.String()), logExtraFields)
+	}
+
+	return handedOff
+}
+
+// sendTransferRequest requests the object transfer to the server, marking the request as forwarded,
+// and returns the transfer id in the server.
+func (ow *ObjectWorkerT) sendTransferRequest(server pools.ServerT, object objectStorage.ObjectT) (id string, err error) {
+	bodyBytes, err := json.Marshal(object)
+	if err != nil {
+		return id, err
+	}
+
+	port := server.Port
+	if port == "" {
+		port = ow.config.APIService.Port
+	}
+
+	requestURL := fmt.Sprintf("http://%s%s",
+		net.JoinHostPort(server.Address, port),
+		global.EndpointRequestTransfer,
+	)
+	req, err := http.NewRequestWithContext(ow.ctx, http.MethodPost, requestURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return id, err
+	}
+	req.Header.Set(global.HeaderContentType, global.HeaderContentTypeAppJson)
+	req.Header.Set(global.HeaderForwardedBy, ow.config.Name)
+
+	res, err := ow.httpClient.Do(req)
+	if err != nil {
+		return id, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("instance '%s' responded with '%s' status", server.Name, res.Status)
+		return id, err
+	}
+
+	response := struct {
+		Id string `json:"id"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&response)
+
+	return response.Id, err
+}
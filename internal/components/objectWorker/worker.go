@@ -2,14 +2,22 @@ package objectWorker
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"bot/api/v1alpha3"
 	"bot/internal/global"
 	"bot/internal/logger"
+	"bot/internal/managers/hashring"
 	"bot/internal/managers/objectStorage"
+	"bot/internal/managers/routing"
+	"bot/internal/metrics"
 	"bot/internal/pools"
+	"bot/internal/retry"
 )
 
 type ObjectWorkerT struct {
@@ -17,23 +25,41 @@ type ObjectWorkerT struct {
 	config *v1alpha3.BOTConfigT
 	log    logger.LoggerT
 
-	// ObjectManager objectStorage.ManagerT
-	// hashring            *hashring.HashRingT
+	hashring            *hashring.HashRingT
 	objectRequestPool   *pools.ObjectRequestPoolT
 	databaseRequestPool *pools.DatabaseRequestPoolT
-	// serverInstancePool  *pools.ServerInstancesPoolT
-
-	sources map[string]objectStorage.ObjectManagerI
+	transferStatusPool  *pools.TransferStatusPoolT
+	deadLetterPool      *pools.DeadLetterPoolT
+	serverInstancePool  *pools.ServerInstancesPoolT
+
+	retryPolicy *retry.PolicyT
+	router      *routing.RouterT
+	sources     map[string]objectStorage.ObjectManagerI
+	httpClient  *http.Client
+
+	// stopped ends the worker flow after the requests in process,
+	// flowWg waits for it in the shutdown
+	stopped atomic.Bool
+	flowWg  sync.WaitGroup
 }
 
 // WORKER Functions
 
-func NewObjectWorker(config *v1alpha3.BOTConfigT, objectPool *pools.ObjectRequestPoolT, dbPool *pools.DatabaseRequestPoolT) (ow *ObjectWorkerT, err error) {
+func NewObjectWorker(config *v1alpha3.BOTConfigT, objectPool *pools.ObjectRequestPoolT, dbPool *pools.DatabaseRequestPoolT,
+	statusPool *pools.TransferStatusPoolT, deadLetterPool *pools.DeadLetterPoolT,
+	serverPool *pools.ServerInstancesPoolT, hashring *hashring.HashRingT) (ow *ObjectWorkerT, err error) {
 	ow = &ObjectWorkerT{
 		ctx:                 context.Background(),
 		config:              config,
+		hashring:            hashring,
 		objectRequestPool:   objectPool,
 		databaseRequestPool: dbPool,
+		transferStatusPool:  statusPool,
+		deadLetterPool:      deadLetterPool,
+		serverInstancePool:  serverPool,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
 	}
 
 	logCommon := global.GetLogCommonFields()
@@ -44,6 +70,13 @@ func NewObjectWorker(config *v1alpha3.BOTConfigT, objectPool *pools.ObjectReques
 		logCommon,
 	)
 
+	ow.retryPolicy, err = retry.NewPolicy(ow.config.ObjectWorker.Retry)
+	if err != nil {
+		return ow, err
+	}
+
+	ow.router = routing.NewRouter(&ow.config.ObjectWorker)
+
 	ow.sources = map[string]objectStorage.ObjectManagerI{}
 	for _, sv := range config.ObjectWorker.Sources {
 		ow.sources[sv.Name], err = objectStorage.GetManager(ow.ctx, sv)
@@ -57,54 +90,63 @@ func NewObjectWorker(config *v1alpha3.BOTConfigT, objectPool *pools.ObjectReques
 
 func (ow *ObjectWorkerT) Run() {
 	global.ServerState.SetObjectReady()
+	ow.flowWg.Add(1)
 	go ow.flow()
-}
 
-func (ow *ObjectWorkerT) Shutdown() {
-	// logExtraFields := maps.Clone(global.LogExtraFields)
+	for _, sv := range ow.config.ObjectWorker.Sources {
+		if cleaner, ok := ow.sources[sv.Name].(objectStorage.UploadCleanerI); ok {
+			go ow.uploadCleanupFlow(sv, cleaner)
+		}
+	}
+}
 
-	// transferRequestPool := ow.objectRequestPool.GetPool()
-	// for key, request := range transferRequestPool {
-	// 	ow.objectRequestPool.RemoveRequest(key)
+// Shutdown waits for the requests in the pool to be processed until the drain timeout,
+// and stops the worker flow. It returns the requests left in the pool, to be handed off
+// with HandOff or kept in the pool to be replayed by a persisted pool.
+func (ow *ObjectWorkerT) Shutdown() (remaining int) {
+	logExtraFields := global.GetLogExtraFieldsObjectWorker()
 
-	// 	logExtraFields[global.LogFieldKeyExtraCurrentRequest] = request.String()
-	// 	ow.log.Debug("process object transfer request", logExtraFields)
+	deadline := time.Now().Add(ow.config.Shutdown.DrainTimeout)
+	for len(ow.objectRequestPool.GetPool()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+	}
 
-	// 	// if global.Config.HashRingWorker.Enabled {
-	// 	// 	serverName := global.HashRing.GetNode(request.To.ObjectPath)
+	// the requests in process are finished before the hand off
+	ow.stopped.Store(true)
+	ow.flowWg.Wait()
 
-	// 	// 	if serverName != global.Config.Name {
-	// 	// 		// send transfer request to owner
-	// 	// 		logger.Logger.Infof("moving object transfer request '%s' to '%s'", request.String(), serverName)
+	remaining = len(ow.objectRequestPool.GetPool())
+	logExtraFields[global.LogFieldKeyExtraCurrentPoolLength] = remaining
+	ow.log.Info("object worker stopped", logExtraFields)
 
-	// 	// 		err := ow.moveTransferRequest(serverName, request)
-	// 	// 		if err == nil {
-	// 	// 			return
-	// 	// 		}
+	return remaining
+}
 
-	// 	// 		logger.Logger.Errorf("unable to move object transfer request '%s' to '%s': %s", request.String(), serverName, err.Error())
-	// 	// 	}
-	// 	// }
+// HandOff forwards the requests left in the pool of the stopped worker to their new hashring
+// owners when the hand off is enabled, and returns the requests handed off and the remaining ones.
+// The hashring updates must be stopped before, so this instance is not added to it again.
+func (ow *ObjectWorkerT) HandOff() (handedOff int, remaining int) {
+	remaining = len(ow.objectRequestPool.GetPool())
+	if remaining > 0 && ow.config.HashRingWorker.Enabled && ow.config.Shutdown.HandOff {
+		handedOff = ow.handOffRequests()
+		remaining = len(ow.objectRequestPool.GetPool())
+	}
 
-	// 	err := ow.executeTransferRequest(request)
-	// 	if err != nil {
-	// 		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
-	// 		ow.log.Error("unable to process object transfer request", logExtraFields)
-	// 	} else {
-	// 		ow.log.Debug("success in process object transfer request", logExtraFields)
-	// 	}
-	// }
+	return handedOff, remaining
 }
 
 func (ow *ObjectWorkerT) flow() {
+	defer ow.flowWg.Done()
+
 	logExtraFields := global.GetLogExtraFieldsObjectWorker()
 
 	emptyPoolLog := true
-	for {
+	for !ow.stopped.Load() {
 		// CONSUME OBJECT TO MIGRATE FROM MAP OR WAIT
 		transferRequestPool := ow.objectRequestPool.GetPool()
 
 		poolLen := len(transferRequestPool)
+		metrics.ObjectPoolLength.Set(float64(poolLen))
 		if poolLen == 0 {
 			if emptyPoolLog {
 				ow.log.Debug("object request pool empty", logExtraFields)
@@ -120,9 +162,14 @@ func (ow *ObjectWorkerT) flow() {
 		currentThreads := 0
 		requestIndex := 0
 		requestsCount := 0
-		for key, request := range transferRequestPool {
+		now := time.Now()
+		for _, request := range transferRequestPool {
+			// requests waiting for a retry backoff are kept in the pool
+			if request.NotBefore.After(now) {
+				continue
+			}
+
 			requestList = append(requestList, request)
-			ow.objectRequestPool.RemoveRequest(key)
 			requestsCount++
 
 			if requestIndex++; requestIndex >= ow.config.ObjectWorker.RequestsByChildThread {
@@ -141,6 +188,11 @@ func (ow *ObjectWorkerT) flow() {
 			currentThreads++
 		}
 
+		if requestsCount == 0 {
+			time.Sleep(2 * time.Second)
+			continue
+		}
+
 		wg := sync.WaitGroup{}
 		for _, requests := range threadList {
 			wg.Add(1)
@@ -153,7 +205,14 @@ func (ow *ObjectWorkerT) flow() {
 		logExtraFields[global.LogFieldKeyExtraCurrentPoolLength] = poolLen - requestsCount
 		ow.log.Debug("object worker handle requests", logExtraFields)
 
+		metrics.ObjectActiveRequests.Set(float64(requestsCount))
+		metrics.ObjectActiveThreads.Set(float64(currentThreads))
+		metrics.ObjectPoolLength.Set(float64(poolLen - requestsCount))
+
 		wg.Wait()
+
+		metrics.ObjectActiveRequests.Set(0)
+		metrics.ObjectActiveThreads.Set(0)
 	}
 }
 
@@ -163,44 +222,214 @@ func (ow *ObjectWorkerT) processRequestList(wg *sync.WaitGroup, requests []pools
 	logExtraFields := global.GetLogExtraFieldsObjectWorker()
 
 	for _, request := range requests {
-		back, backSource, err := ow.getBackendObject(request.Object)
-		if err != nil {
-			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
-			ow.log.Error("unable to get backend object route", logExtraFields)
-			continue
+		logExtraFields[global.LogFieldKeyExtraTransferId] = request.Id
+		logExtraFields[global.LogFieldKeyExtraObject] = request.Object.String()
+
+		// the requests of objects owned by other hashring instance are forwarded to it
+		if !ow.forwardRequest(request) {
+			retryable, err := ow.processRequest(request)
+			if err != nil {
+				request.Attempts++
+				if retryable && ow.retryPolicy.ShouldRetry(request.Attempts, err) {
+					// the request is kept in the pool with the time for the next attempt
+					request.NotBefore = time.Now().Add(ow.retryPolicy.Backoff(request.Attempts))
+					ow.transferStatusPool.SetState(request.Id, pools.TransferStateRetrying, err.Error())
+
+					logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+					logExtraFields[global.LogFieldKeyExtraAttempts] = request.Attempts
+					ow.log.Warn("object transfer request scheduled to retry", logExtraFields)
+
+					// a newer request for the object added while processing it takes its place
+					replaced, err := ow.objectRequestPool.ReplaceRequest(request)
+					if err != nil {
+						logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+						ow.log.Error("unable to schedule object request retry in pool", logExtraFields)
+					}
+					if !replaced && err == nil {
+						ow.transferStatusPool.SetState(request.Id, pools.TransferStateSkipped, "superseded by a newer transfer request")
+						ow.log.Info("object transfer request retry superseded by a newer request", logExtraFields)
+					}
+					continue
+				}
+
+				ow.transferStatusPool.SetState(request.Id, pools.TransferStateFailed, err.Error())
+				ow.addDeadLetter(request, err)
+			}
 		}
-		front, frontSource, err := ow.getFrontendObject(request.Object)
+
+		// the request is removed from the pool only when it is processed,
+		// so a persisted pool can replay it if the process dies in the middle
+		_, err := ow.objectRequestPool.RemoveProcessedRequest(request)
 		if err != nil {
 			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
-			ow.log.Error("unable to get frontend object route", logExtraFields)
-			continue
+			ow.log.Error("unable to remove object request from pool", logExtraFields)
 		}
-		logExtraFields[global.LogFieldKeyExtraError] = global.LogFieldValueDefault
-		logExtraFields[global.LogFieldKeyExtraObject] = front.String()
-		logExtraFields[global.LogFieldKeyExtraBackendObject] = back.String()
-		ow.log.Info("process object transfer request", logExtraFields)
+	}
+}
 
-		backobj, err := ow.sources[backSource].GetObject(back)
+func (ow *ObjectWorkerT) addDeadLetter(request pools.ObjectRequestT, reqErr error) {
+	logExtraFields := global.GetLogExtraFieldsObjectWorker()
+	logExtraFields[global.LogFieldKeyExtraTransferId] = request.Id
+	logExtraFields[global.LogFieldKeyExtraObject] = request.Object.String()
+	logExtraFields[global.LogFieldKeyExtraAttempts] = request.Attempts
+	logExtraFields[global.LogFieldKeyExtraError] = reqErr.Error()
+
+	err := ow.deadLetterPool.AddLetter(pools.DeadLetterT{
+		Id:            request.Id,
+		Kind:          pools.DeadLetterKindObject,
+		Error:         reqErr.Error(),
+		Attempts:      request.Attempts,
+		FailedAt:      time.Now(),
+		ObjectRequest: &request,
+	})
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to add object request in dead letter pool", logExtraFields)
+		return
+	}
+
+	ow.log.Error("object transfer request moved to dead letter pool", logExtraFields)
+}
+
+// processRequest executes the transfer of the requested object. On error, it also
+// returns if the failure is retryable (routing errors are not).
+func (ow *ObjectWorkerT) processRequest(request pools.ObjectRequestT) (retryable bool, err error) {
+	logExtraFields := global.GetLogExtraFieldsObjectWorker()
+	logExtraFields[global.LogFieldKeyExtraTransferId] = request.Id
+	logExtraFields[global.LogFieldKeyExtraObject] = request.Object.String()
+
+	ow.transferStatusPool.SetState(request.Id, pools.TransferStateRouting, "")
+	back, backSource, err := ow.router.GetBackendObject(request.Object)
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to get backend object route", logExtraFields)
+		return false, err
+	}
+	front, frontSource, err := ow.router.GetFrontendObject(request.Object)
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to get frontend object route", logExtraFields)
+		return false, err
+	}
+	logExtraFields[global.LogFieldKeyExtraError] = global.LogFieldValueDefault
+	logExtraFields[global.LogFieldKeyExtraObject] = front.String()
+	logExtraFields[global.LogFieldKeyExtraBackendObject] = back.String()
+	ow.log.Info("process object transfer request", logExtraFields)
+
+	skip, reason, err := ow.checkRoutePolicy(request.Object, back, backSource, front, frontSource)
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to check route policy", logExtraFields)
+		return true, err
+	}
+
+	if skip {
+		metrics.ObjectTransfersSkipped.WithLabelValues(backSource, frontSource).Inc()
+		ow.transferStatusPool.SetState(request.Id, pools.TransferStateSkipped, reason)
+		ow.log.Info(fmt.Sprintf("skip object transfer request: %s", reason), logExtraFields)
+		return false, err
+	}
+
+	// the objects are copied inside the backend when the front and backend sources share it
+	if copier, ok := ow.getServerCopier(backSource, frontSource); ok {
+		return ow.processServerCopy(request, copier, back, backSource, front, frontSource)
+	}
+
+	ow.transferStatusPool.SetState(request.Id, pools.TransferStateCopying, "")
+	transferStart := time.Now()
+	backobj, err := ow.sources[backSource].GetObject(back)
+	if err != nil {
+		metrics.ObjectTransferDuration.WithLabelValues(backSource, frontSource, metrics.ResultFailure).
+			Observe(time.Since(transferStart).Seconds())
+
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to get backend object", logExtraFields)
+		return true, err
+	}
+	defer backobj.Close()
+
+	// the md5 is computed while copying when the backend does not know it
+	algorithms := []string{}
+	if ow.config.ObjectWorker.Verification.Enabled {
+		algorithms = slices.Clone(ow.config.ObjectWorker.Verification.Algorithms)
+	}
+	if backobj.GetMD5String() == "" && !slices.Contains(algorithms, objectStorage.ChecksumAlgorithmMD5) {
+		algorithms = append(algorithms, objectStorage.ChecksumAlgorithmMD5)
+	}
+
+	countobj := &countingObjectT{ObjectI: backobj}
+	var putobj objectStorage.ObjectI = countobj
+	checksums := map[string]string{}
+	var hashobj *hashingObjectT
+	if len(algorithms) > 0 {
+		hashobj, err = newHashingObject(countobj, algorithms)
 		if err != nil {
 			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
-			ow.log.Error("unable to get backend object", logExtraFields)
-			continue
+			ow.log.Error("unable to hash backend object", logExtraFields)
+			return false, err
 		}
-		defer backobj.Close()
+		putobj = hashobj
+	}
+	err = ow.sources[frontSource].PutObject(front, putobj)
+
+	result := metrics.GetResult(err)
+	metrics.ObjectTransferBytes.WithLabelValues(backSource, frontSource, result).Add(float64(countobj.bytesRead))
+	metrics.ObjectTransferDuration.WithLabelValues(backSource, frontSource, result).
+		Observe(time.Since(transferStart).Seconds())
+
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to put frontend object", logExtraFields)
+		return true, err
+	}
+
+	if hashobj != nil {
+		checksums = hashobj.GetChecksums()
+	}
 
-		err = ow.sources[frontSource].PutObject(front, backobj)
+	if ow.config.ObjectWorker.Verification.Enabled {
+		err = ow.verifyTransfer(backobj, countobj.bytesRead, checksums, front, frontSource)
 		if err != nil {
+			metrics.ObjectVerificationFailures.WithLabelValues(backSource, frontSource).Inc()
 			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
-			ow.log.Error("unable to put frontend object", logExtraFields)
-			continue
+			ow.log.Error("unable to verify object transfer integrity", logExtraFields)
+			return true, err
 		}
+	}
+
+	// the computed md5 is the content one, also for the objects uploaded in multiple parts
+	md5 := backobj.GetMD5String()
+	if checksum, ok := checksums[objectStorage.ChecksumAlgorithmMD5]; ok {
+		md5 = checksum
+	}
 
-		ow.databaseRequestPool.AddRequest(pools.DatabaseRequestT{
-			BucketName: front.Bucket,
-			ObjectPath: front.Path,
-			MD5:        backobj.GetMD5String(),
-		})
+	checksumAlgorithm, checksum := backobj.GetChecksum()
+	if checksum == "" {
+		checksumAlgorithm, checksum = objectStorage.ChecksumAlgorithmMD5, md5
+	}
 
-		ow.log.Info("success in process object transfer request", logExtraFields)
+	err = ow.databaseRequestPool.AddRequest(pools.DatabaseRequestT{
+		TransferId: request.Id,
+		BucketName: front.Bucket,
+		ObjectPath: front.Path,
+		MD5:        md5,
+
+		ChecksumAlgorithm: checksumAlgorithm,
+		Checksum:          checksum,
+		Size:              countobj.bytesRead,
+		ContentType:       backobj.GetContentType(),
+		Source:            backSource,
+		TransferredAt:     time.Now(),
+		Instance:          ow.config.Name,
+	})
+	if err != nil {
+		logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+		ow.log.Error("unable to add database request in pool", logExtraFields)
+		return true, err
 	}
+
+	ow.transferStatusPool.SetState(request.Id, pools.TransferStateCopied, "")
+	ow.log.Info("success in process object transfer request", logExtraFields)
+
+	return false, err
 }
@@ -1,100 +1,176 @@
 package objectWorker
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
 	"bot/api/v1alpha3"
+	"bot/internal/global"
 	"bot/internal/managers/objectStorage"
-	"fmt"
-	"strings"
+	"bot/internal/managers/routing"
 )
 
-func (ow *ObjectWorkerT) getBackendObject(object objectStorage.ObjectT) (backend objectStorage.ObjectT, source string, err error) {
-	backend = objectStorage.ObjectT{}
-	found := false
-	route := v1alpha3.RouteConfigT{}
-	switch ow.config.ObjectWorker.Routing.Type {
-	case "bucket":
-		{
-			if route, found = ow.config.ObjectWorker.Routing.Routes[object.Bucket]; found {
-				source = route.Backend.Source
-				for _, modv := range route.Backend.Modifiers {
-					for _, modcongv := range ow.config.ObjectWorker.Modifiers {
-						if modcongv.Name == modv {
-							backend.Bucket = modcongv.Bucket
-							backend.Path = modcongv.AddPrefix + strings.TrimPrefix(object.Path, modcongv.RemovePrefix)
-						}
-					}
-				}
-			}
-		}
-	case "metadata":
-		{
-			conditionKey := object.Metadata.Get(ow.config.ObjectWorker.Routing.MetadataKey)
-			if route, found = ow.config.ObjectWorker.Routing.Routes[conditionKey]; found {
-				source = route.Backend.Source
-				for _, modv := range route.Backend.Modifiers {
-					for _, modcongv := range ow.config.ObjectWorker.Modifiers {
-						if modcongv.Name == modv {
-							backend.Bucket = modcongv.Bucket
-							backend.Path = modcongv.AddPrefix + strings.TrimPrefix(object.Path, modcongv.RemovePrefix)
-						}
-					}
-				}
-			}
+// countingObjectT wraps a backend object to count the bytes read from it.
+type countingObjectT struct {
+	objectStorage.ObjectI
+	bytesRead int64
+}
+
+func (o *countingObjectT) Read(p []byte) (n int, err error) {
+	n, err = o.ObjectI.Read(p)
+	o.bytesRead += int64(n)
+	return n, err
+}
+
+// hashingObjectT wraps a backend object to hash its content while it is read.
+type hashingObjectT struct {
+	objectStorage.ObjectI
+	hashes map[string]hash.Hash
+}
+
+func newHashingObject(obj objectStorage.ObjectI, algorithms []string) (o *hashingObjectT, err error) {
+	o = &hashingObjectT{
+		ObjectI: obj,
+		hashes:  map[string]hash.Hash{},
+	}
+
+	for _, algorithm := range algorithms {
+		o.hashes[algorithm], err = objectStorage.NewChecksumHash(algorithm)
+		if err != nil {
+			return o, err
 		}
 	}
-	if !found {
-		return backend, source, fmt.Errorf("not route for backend object")
+
+	return o, err
+}
+
+func (o *hashingObjectT) Read(p []byte) (n int, err error) {
+	n, err = o.ObjectI.Read(p)
+	for _, h := range o.hashes {
+		h.Write(p[:n])
 	}
+	return n, err
+}
 
-	if backend.Bucket == "" || backend.Path == "" {
-		err = fmt.Errorf("empty bucket or path object, check modifiers")
+// GetChecksums returns the hex encoded checksums of the content read by algorithm.
+func (o *hashingObjectT) GetChecksums() (checksums map[string]string) {
+	checksums = map[string]string{}
+	for algorithm, h := range o.hashes {
+		checksums[algorithm] = hex.EncodeToString(h.Sum(nil))
 	}
+	return checksums
+}
+
+// uploadCleanupFlow aborts the abandoned uploads of the source periodically,
+// as the interrupted uploads are kept when they are resumable.
+func (ow *ObjectWorkerT) uploadCleanupFlow(source v1alpha3.SourceConfigT, cleaner objectStorage.UploadCleanerI) {
+	for !ow.stopped.Load() {
+		time.Sleep(source.Upload.CleanupInterval)
 
-	return backend, source, err
+		logExtraFields := global.GetLogExtraFieldsObjectWorker()
+		aborted, err := cleaner.CleanUploads()
+		if err != nil {
+			logExtraFields[global.LogFieldKeyExtraError] = err.Error()
+			ow.log.Error(fmt.Sprintf("unable to clean abandoned uploads in '%s' source", source.Name), logExtraFields)
+		}
+
+		if aborted > 0 {
+			ow.log.Info(fmt.Sprintf("%d abandoned uploads aborted in '%s' source", aborted, source.Name), logExtraFields)
+		}
+	}
 }
 
-func (ow *ObjectWorkerT) getFrontendObject(object objectStorage.ObjectT) (frontend objectStorage.ObjectT, source string, err error) {
-	frontend = objectStorage.ObjectT{}
-	found := false
-	route := v1alpha3.RouteConfigT{}
-	switch ow.config.ObjectWorker.Routing.Type {
-	case "bucket":
-		{
-			if route, found = ow.config.ObjectWorker.Routing.Routes[object.Bucket]; found {
-				source = route.Front.Source
-				for _, modv := range route.Front.Modifiers {
-					for _, modcongv := range ow.config.ObjectWorker.Modifiers {
-						if modcongv.Name == modv {
-							frontend.Bucket = modcongv.Bucket
-							frontend.Path = modcongv.AddPrefix + strings.TrimPrefix(object.Path, modcongv.RemovePrefix)
-						}
-					}
-				}
-			}
+// checkRoutePolicy returns if the transfer must be skipped, and why, following
+// the policy of the route. The backend object is only checked when the frontend
+// object exists, to avoid the backend requests when they are not needed.
+func (ow *ObjectWorkerT) checkRoutePolicy(object objectStorage.ObjectT,
+	back objectStorage.ObjectT, backSource string,
+	front objectStorage.ObjectT, frontSource string) (skip bool, reason string, err error) {
+	route, _ := ow.router.GetRoute(object)
+	if route.Policy == "" || route.Policy == routing.PolicyOverwrite {
+		return skip, reason, err
+	}
+
+	frontInfo, err := ow.sources[frontSource].StatObject(front)
+	if err != nil {
+		if errors.Is(err, objectStorage.ErrObjectNotFound) {
+			err = nil
+		}
+		return skip, reason, err
+	}
+
+	if route.Policy == routing.PolicySkipIfExists {
+		return true, "frontend object already exists", err
+	}
+
+	backInfo, err := ow.sources[backSource].StatObject(back)
+	if err != nil {
+		return skip, reason, err
+	}
+
+	if frontInfo.Size != backInfo.Size {
+		return skip, reason, err
+	}
+
+	// the objects are only identical with a checksum available in both of them,
+	// as the multipart and composite objects can lack the md5
+	for _, algorithm := range []string{objectStorage.ChecksumAlgorithmMD5, objectStorage.ChecksumAlgorithmSHA256, objectStorage.ChecksumAlgorithmCRC32C} {
+		frontChecksum, backChecksum := frontInfo.GetChecksum(algorithm), backInfo.GetChecksum(algorithm)
+		if algorithm == objectStorage.ChecksumAlgorithmMD5 && (!objectStorage.IsMD5(frontChecksum) || !objectStorage.IsMD5(backChecksum)) {
+			continue
+		}
+		if frontChecksum == "" || backChecksum == "" {
+			continue
 		}
-	case "metadata":
-		{
-			conditionKey := object.Metadata.Get(ow.config.ObjectWorker.Routing.MetadataKey)
-			if route, found = ow.config.ObjectWorker.Routing.Routes[conditionKey]; found {
-				source = route.Front.Source
-				for _, modv := range route.Front.Modifiers {
-					for _, modcongv := range ow.config.ObjectWorker.Modifiers {
-						if modcongv.Name == modv {
-							frontend.Bucket = modcongv.Bucket
-							frontend.Path = modcongv.AddPrefix + strings.TrimPrefix(object.Path, modcongv.RemovePrefix)
-						}
-					}
-				}
-			}
+
+		if frontChecksum == backChecksum {
+			skip = true
+			reason = fmt.Sprintf("frontend object is identical to backend object (size: %d, %s: %s)",
+				backInfo.Size, algorithm, backChecksum)
 		}
+		return skip, reason, err
 	}
-	if !found {
-		return frontend, source, fmt.Errorf("not route for backend object")
+
+	return skip, reason, err
+}
+
+// verifyTransfer compares the size and the checksums of the content copied with the ones
+// reported by the backend object and the ones stored in the frontend object.
+// The checksums not available in an object are not compared, nor the backend size when it is unknown.
+func (ow *ObjectWorkerT) verifyTransfer(backobj objectStorage.ObjectI, size int64, checksums map[string]string,
+	front objectStorage.ObjectT, frontSource string) (err error) {
+	if backobj.GetSize() >= 0 && size != backobj.GetSize() {
+		err = fmt.Errorf("integrity mismatch in backend object size (expected: %d, copied: %d)", backobj.GetSize(), size)
+		return err
+	}
+
+	if checksum, ok := checksums[objectStorage.ChecksumAlgorithmMD5]; ok && objectStorage.IsMD5(backobj.GetMD5String()) {
+		if checksum != backobj.GetMD5String() {
+			err = fmt.Errorf("integrity mismatch in backend object md5 (expected: %s, copied: %s)", backobj.GetMD5String(), checksum)
+			return err
+		}
 	}
 
-	if frontend.Bucket == "" || frontend.Path == "" {
-		err = fmt.Errorf("empty bucket or path object, check modifiers")
+	frontInfo, err := ow.sources[frontSource].StatObject(front)
+	if err != nil {
+		return err
+	}
+
+	if size != frontInfo.Size {
+		err = fmt.Errorf("integrity mismatch in frontend object size (expected: %d, stored: %d)", size, frontInfo.Size)
+		return err
+	}
+
+	for algorithm, checksum := range checksums {
+		stored := frontInfo.GetChecksum(algorithm)
+		if stored != "" && stored != checksum {
+			err = fmt.Errorf("integrity mismatch in frontend object %s (expected: %s, stored: %s)", algorithm, checksum, stored)
+			return err
+		}
 	}
 
-	return frontend, source, err
+	return err
 }
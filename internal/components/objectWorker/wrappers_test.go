@@ -0,0 +1,274 @@
+package objectWorker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"bot/api/v1alpha3"
+	"bot/internal/managers/objectStorage"
+	"bot/internal/managers/routing"
+)
+
+// testObjectT is an object with its content in memory.
+type testObjectT struct {
+	io.Reader
+	size int64
+	md5  string
+}
+
+func (o *testObjectT) Close() error                                     { return nil }
+func (o *testObjectT) GetContentType() string                           { return "application/octet-stream" }
+func (o *testObjectT) GetSize() int64                                   { return o.size }
+func (o *testObjectT) GetMD5String() string                             { return o.md5 }
+func (o *testObjectT) GetChecksum() (algorithm string, checksum string) { return "", "" }
+
+func TestCheckRoutePolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		backContent  string
+		frontContent string
+		// frontMissing does not put the frontend object
+		frontMissing bool
+
+		expectedSkip bool
+	}{
+		{
+			name:         "default policy overwrites",
+			policy:       "",
+			backContent:  "content",
+			frontContent: "content",
+			expectedSkip: false,
+		},
+		{
+			name:         "overwrite",
+			policy:       routing.PolicyOverwrite,
+			backContent:  "content",
+			frontContent: "content",
+			expectedSkip: false,
+		},
+		{
+			name:         "skip if exists with existing object",
+			policy:       routing.PolicySkipIfExists,
+			backContent:  "content",
+			frontContent: "other",
+			expectedSkip: true,
+		},
+		{
+			name:         "skip if exists with missing object",
+			policy:       routing.PolicySkipIfExists,
+			backContent:  "content",
+			frontMissing: true,
+			expectedSkip: false,
+		},
+		{
+			name:         "skip if identical with identical object",
+			policy:       routing.PolicySkipIfIdentical,
+			backContent:  "content",
+			frontContent: "content",
+			expectedSkip: true,
+		},
+		{
+			name:         "skip if identical with same size object",
+			policy:       routing.PolicySkipIfIdentical,
+			backContent:  "content",
+			frontContent: "CONTENT",
+			expectedSkip: false,
+		},
+		{
+			name:         "skip if identical with other size object",
+			policy:       routing.PolicySkipIfIdentical,
+			backContent:  "content",
+			frontContent: "other content",
+			expectedSkip: false,
+		},
+		{
+			name:         "skip if identical with missing object",
+			policy:       routing.PolicySkipIfIdentical,
+			backContent:  "content",
+			frontMissing: true,
+			expectedSkip: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := v1alpha3.ObjectWorkerConfigT{
+				Modifiers: []v1alpha3.ModifierConfigT{
+					{Name: "front", Bucket: "front-bucket"},
+					{Name: "backend", Bucket: "backend-bucket"},
+				},
+				Routing: v1alpha3.RoutingConfigT{
+					Type: "bucket",
+					Routes: map[string]v1alpha3.RouteConfigT{
+						"bucket": {
+							Policy:  test.policy,
+							Front:   v1alpha3.RouteObjConfigT{Source: "front", Modifiers: []string{"front"}},
+							Backend: v1alpha3.RouteObjConfigT{Source: "backend", Modifiers: []string{"backend"}},
+						},
+					},
+				},
+			}
+
+			ow := &ObjectWorkerT{
+				router:  routing.NewRouter(&config),
+				sources: map[string]objectStorage.ObjectManagerI{},
+			}
+			for _, name := range []string{"front", "backend"} {
+				source, err := objectStorage.GetManager(context.Background(), v1alpha3.SourceConfigT{
+					Name: t.Name() + "/" + name,
+					Type: objectStorage.TypeMemory,
+				})
+				if err != nil {
+					t.Fatalf("unable to get %s source: %s", name, err.Error())
+				}
+				ow.sources[name] = source
+			}
+
+			object := objectStorage.ObjectT{Bucket: "bucket", Path: "object"}
+			back, backSource, err := ow.router.GetBackendObject(object)
+			if err != nil {
+				t.Fatalf("unable to route backend object: %s", err.Error())
+			}
+			front, frontSource, err := ow.router.GetFrontendObject(object)
+			if err != nil {
+				t.Fatalf("unable to route frontend object: %s", err.Error())
+			}
+
+			contents := map[string]string{backSource: test.backContent}
+			if !test.frontMissing {
+				contents[frontSource] = test.frontContent
+			}
+			for source, content := range contents {
+				obj := back
+				if source == frontSource {
+					obj = front
+				}
+				err = ow.sources[source].PutObject(obj, &testObjectT{Reader: bytes.NewReader([]byte(content)), size: int64(len(content))})
+				if err != nil {
+					t.Fatalf("unable to put %s object: %s", source, err.Error())
+				}
+			}
+
+			skip, reason, err := ow.checkRoutePolicy(object, back, backSource, front, frontSource)
+			if err != nil {
+				t.Fatalf("unable to check route policy: %s", err.Error())
+			}
+			if skip != test.expectedSkip {
+				t.Fatalf("skip is %t (%s), expected %t", skip, reason, test.expectedSkip)
+			}
+			if skip && reason == "" {
+				t.Fatalf("transfer skipped without reason")
+			}
+		})
+	}
+}
+
+func TestVerifyTransfer(t *testing.T) {
+	const (
+		contentMD5 = "9a0364b9e99bb480dd25e1f0284c8555"
+		otherMD5   = "d41d8cd98f00b204e9800998ecf8427e"
+	)
+
+	tests := []struct {
+		name string
+		// backSize and backMD5 are the ones reported by the backend object
+		backSize int64
+		backMD5  string
+		// copied is the content copied, and stored the one of the frontend object
+		copied string
+		stored string
+
+		expectedErr bool
+	}{
+		{
+			name:     "identical content",
+			backSize: 7,
+			backMD5:  contentMD5,
+			copied:   "content",
+			stored:   "content",
+		},
+		{
+			name:     "unknown backend size and md5",
+			backSize: -1,
+			copied:   "content",
+			stored:   "content",
+		},
+		{
+			name:     "multipart etag is not compared",
+			backSize: 7,
+			backMD5:  contentMD5 + "-2",
+			copied:   "content",
+			stored:   "content",
+		},
+		{
+			name:        "backend size mismatch",
+			backSize:    8,
+			copied:      "content",
+			stored:      "content",
+			expectedErr: true,
+		},
+		{
+			name:        "backend md5 mismatch",
+			backSize:    7,
+			backMD5:     otherMD5,
+			copied:      "content",
+			stored:      "content",
+			expectedErr: true,
+		},
+		{
+			name:        "frontend size mismatch",
+			backSize:    7,
+			copied:      "content",
+			stored:      "content!",
+			expectedErr: true,
+		},
+		{
+			name:        "frontend md5 mismatch",
+			backSize:    7,
+			copied:      "content",
+			stored:      "CONTENT",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			front, err := objectStorage.GetManager(context.Background(), v1alpha3.SourceConfigT{
+				Name: t.Name(),
+				Type: objectStorage.TypeMemory,
+			})
+			if err != nil {
+				t.Fatalf("unable to get source: %s", err.Error())
+			}
+			ow := &ObjectWorkerT{
+				sources: map[string]objectStorage.ObjectManagerI{"front": front},
+			}
+
+			frontObj := objectStorage.ObjectT{Bucket: "bucket", Path: "object"}
+			err = front.PutObject(frontObj, &testObjectT{Reader: bytes.NewReader([]byte(test.stored)), size: int64(len(test.stored))})
+			if err != nil {
+				t.Fatalf("unable to put object: %s", err.Error())
+			}
+
+			// the checksums of the content copied are hashed while reading it
+			hashing, err := newHashingObject(&testObjectT{Reader: bytes.NewReader([]byte(test.copied))},
+				[]string{objectStorage.ChecksumAlgorithmMD5})
+			if err != nil {
+				t.Fatalf("unable to hash object: %s", err.Error())
+			}
+			size, err := io.Copy(io.Discard, hashing)
+			if err != nil {
+				t.Fatalf("unable to read object: %s", err.Error())
+			}
+
+			backObj := &testObjectT{size: test.backSize, md5: test.backMD5}
+			err = ow.verifyTransfer(backObj, size, hashing.GetChecksums(), frontObj, "front")
+			if (err != nil) != test.expectedErr {
+				t.Fatalf("verify error is '%v', expected error %t", err, test.expectedErr)
+			}
+		})
+	}
+}
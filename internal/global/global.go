@@ -1,15 +1,40 @@
 package global
 
+var (
+	// Version is the bot version, overridden in build time
+	Version = "0.1.0"
+)
+
 const (
 	HeaderContentType          = "Content-Type"
 	HeaderContentTypeAppJson   = "application/json"
 	HeaderContentTypeTextPlain = "text/plain"
+	// HeaderForwardedBy is set in the requests forwarded by other hashring instance
+	HeaderForwardedBy = "X-Bot-Forwarded-By"
+
+	EndpointHealthz              = "/healthz"
+	EndpointInfo                 = "/info"
+	EndpointMetrics              = "/metrics"
+	EndpointRequestTransfer      = "/transfer"
+	EndpointTransferStatus       = "/transfer/{id}"
+	EndpointRequestTransferBatch = "/transfer/batch"
+	EndpointDeadLetters          = "/deadletters"
+	EndpointDeadLetter           = "/deadletters/{id}"
+	EndpointDeadLetterRetry      = "/deadletters/{id}/retry"
+	EndpointJobs                 = "/jobs"
+	EndpointJob                  = "/jobs/{id}"
+	EndpointJobPause             = "/jobs/{id}/pause"
+	EndpointJobResume            = "/jobs/{id}/resume"
+	EndpointRequestObject        = "/request/object"
+	EndpointRequestDatabase      = "/request/database"
+)
 
-	EndpointHealthz         = "/healthz"
-	EndpointInfo            = "/info"
-	EndpointRequestTransfer = "/transfer"
-	EndpointRequestObject   = "/request/object"
-	EndpointRequestDatabase = "/request/database"
+const (
+	// EnvPodIP, EnvPodName and EnvPodNamespace are the environment variables with the pod IP,
+	// name and namespace, used as default instance address, name and discovery namespace
+	EnvPodIP        = "POD_IP"
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
 )
 
 const (
@@ -19,12 +44,15 @@ const (
 
 	LogFieldKeyExtraError              = "error"
 	LogFieldKeyExtraObject             = "object"
+	LogFieldKeyExtraTransferId         = "transfer_id"
 	LogFieldKeyExtraBackendObject      = "backend_object"
 	LogFieldKeyExtraRequestId          = "request_id"
 	LogFieldKeyExtraRequestList        = "request_list"
 	LogFieldKeyExtraActiveRequestCount = "active_request_count"
 	LogFieldKeyExtraActiveThreadCount  = "active_thread_count"
 	LogFieldKeyExtraCurrentPoolLength  = "current_pool_length"
+	LogFieldKeyExtraAttempts           = "attempts"
+	LogFieldKeyExtraJob                = "job"
 
 	LogFieldValueDefault                 = "none"
 	LogFieldValueService                 = "bot"
@@ -32,6 +60,7 @@ const (
 	LogFieldValueComponentObjectWorker   = "ObjectWorker"
 	LogFieldValueComponentDatabaseWorker = "DatabaseWorker"
 	LogFieldValueComponentHashringWorker = "HashringWorker"
+	LogFieldValueComponentJobWorker      = "JobWorker"
 )
 
 var (
@@ -48,8 +77,9 @@ func GetLogCommonFields() map[string]any {
 
 func GetLogExtraFieldsAPI() map[string]any {
 	return map[string]any{
-		LogFieldKeyExtraError:  LogFieldValueDefault,
-		LogFieldKeyExtraObject: LogFieldValueDefault,
+		LogFieldKeyExtraError:      LogFieldValueDefault,
+		LogFieldKeyExtraObject:     LogFieldValueDefault,
+		LogFieldKeyExtraTransferId: LogFieldValueDefault,
 	}
 }
 
@@ -57,7 +87,9 @@ func GetLogExtraFieldsObjectWorker() map[string]any {
 	return map[string]any{
 		LogFieldKeyExtraError:              LogFieldValueDefault,
 		LogFieldKeyExtraObject:             LogFieldValueDefault,
+		LogFieldKeyExtraTransferId:         LogFieldValueDefault,
 		LogFieldKeyExtraBackendObject:      LogFieldValueDefault,
+		LogFieldKeyExtraAttempts:           LogFieldValueDefault,
 		LogFieldKeyExtraActiveRequestCount: LogFieldValueDefault,
 		LogFieldKeyExtraActiveThreadCount:  LogFieldValueDefault,
 		LogFieldKeyExtraCurrentPoolLength:  LogFieldValueDefault,
@@ -69,6 +101,7 @@ func GetLogExtraFieldsDatabaseWorker() map[string]any {
 		LogFieldKeyExtraError:              LogFieldValueDefault,
 		LogFieldKeyExtraRequestId:          LogFieldValueDefault,
 		LogFieldKeyExtraRequestList:        LogFieldValueDefault,
+		LogFieldKeyExtraAttempts:           LogFieldValueDefault,
 		LogFieldKeyExtraActiveRequestCount: LogFieldValueDefault,
 		LogFieldKeyExtraActiveThreadCount:  LogFieldValueDefault,
 		LogFieldKeyExtraCurrentPoolLength:  LogFieldValueDefault,
@@ -82,3 +115,10 @@ func GetLogExtraFieldsHashringWorker() map[string]any {
 		LogFieldKeyExtraRequestList: LogFieldValueDefault,
 	}
 }
+
+func GetLogExtraFieldsJobWorker() map[string]any {
+	return map[string]any{
+		LogFieldKeyExtraError: LogFieldValueDefault,
+		LogFieldKeyExtraJob:   LogFieldValueDefault,
+	}
+}
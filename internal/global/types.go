@@ -8,11 +8,12 @@ type ServerReadyT struct {
 	object   bool
 	database bool
 	hasring  bool
+	job      bool
 }
 
 func (s *ServerReadyT) IsReady() bool {
 	s.mu.Lock()
-	result := s.api && s.object && s.database && s.hasring
+	result := s.api && s.object && s.database && s.hasring && s.job
 	s.mu.Unlock()
 	return result
 }
@@ -45,6 +46,13 @@ func (s *ServerReadyT) IsHashringReady() bool {
 	return result
 }
 
+func (s *ServerReadyT) IsJobReady() bool {
+	s.mu.Lock()
+	result := s.job
+	s.mu.Unlock()
+	return result
+}
+
 func (s *ServerReadyT) SetAPIReady() {
 	s.mu.Lock()
 	s.api = true
@@ -68,3 +76,9 @@ func (s *ServerReadyT) SetHashringReady() {
 	s.hasring = true
 	s.mu.Unlock()
 }
+
+func (s *ServerReadyT) SetJobReady() {
+	s.mu.Lock()
+	s.job = true
+	s.mu.Unlock()
+}
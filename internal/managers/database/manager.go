@@ -5,15 +5,46 @@ import (
 	"bot/internal/pools"
 	"context"
 	"database/sql"
-	"database/sql/driver"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	TypeMySQL    = "mysql"
+	TypePostgres = "postgres"
+	TypeSQLite   = "sqlite"
+
+	// WriteModeInsertIgnore ignores the objects already recorded
+	WriteModeInsertIgnore = "insertIgnore"
+	// WriteModeUpsert updates the recorded fields of the objects already recorded
+	WriteModeUpsert = "upsert"
+	// WriteModeAppendHistory records a new row in every transfer
+	WriteModeAppendHistory = "appendHistory"
+)
 
-	"github.com/go-sql-driver/mysql"
+var (
+	// tableRegexp matches a table name, optionally qualified with the database or schema name
+	tableRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+	// columnRegexp matches a column name
+	columnRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
 )
 
-type ManagerT struct {
-	Ctx       context.Context
-	Connector driver.Connector
+type DatabaseManagerI interface {
+	Init(ctx context.Context, config v1alpha3.DatabaseT) error
+	// InsertObjectList records the objects in the table following the write mode,
+	// keeping the whole list recorded or not recorded at all.
+	InsertObjectList(objectList []pools.DatabaseRequestT) error
+	// CheckSchema returns an error when any of the configured columns does not exist
+	// in the table, or when the table lacks the object unique index in upsert write mode.
+	CheckSchema() error
+	// Migrate applies the catalog table migrations not applied yet and returns them,
+	// only returning them in dry run mode.
+	Migrate(dryRun bool) ([]MigrationT, error)
+	GetMigrationStatus() ([]MigrationStatusT, error)
+	Close() error
 }
 
 // type QueryObjectResultT struct {
@@ -31,51 +62,334 @@ type ObjectT struct {
 	MD5    string
 }
 
-func NewManager(ctx context.Context, db v1alpha3.DatabaseT) (man ManagerT, err error) {
-	man.Ctx = ctx
+func GetManager(ctx context.Context, config v1alpha3.DatabaseT) (m DatabaseManagerI, err error) {
+	switch config.Type {
+	case TypeMySQL:
+		{
+			m = &MySQLManagerT{}
+		}
+	case TypePostgres:
+		{
+			m = &PostgresManagerT{}
+		}
+	case TypeSQLite:
+		{
+			m = &SQLiteManagerT{}
+		}
+	default:
+		{
+			err = fmt.Errorf("database type '%s' not supported", config.Type)
+			return m, err
+		}
+	}
+	err = m.Init(ctx, config)
+	return m, err
+}
+
+// sqlManagerT implements the inserts shared by the SQL databases,
+// with the differences between them defined in its dialect.
+type sqlManagerT struct {
+	ctx     context.Context
+	db      *sql.DB
+	dialect sqlDialectT
+	config  v1alpha3.DatabaseT
+
+	// table and migrationsTable are quoted to be used in the queries,
+	// tableName is the unqualified and unquoted table name
+	table           string
+	tableName       string
+	migrationsTable string
+	columns         []columnT
 
-	if db.Table == "" {
+	// insertPrefix and insertSuffix surround the insert columns and values
+	// to apply the write mode
+	insertPrefix string
+	insertSuffix string
+}
+
+// columnT is a table column with the function to get its value from the requests.
+type columnT struct {
+	name  string
+	value func(object pools.DatabaseRequestT) any
+}
+
+type sqlDialectT struct {
+	// quote is the character used to quote the identifiers
+	quote string
+	// insertIgnorePrefix and insertIgnoreSuffix surround the insert columns and values
+	// to ignore the rows already inserted
+	insertIgnorePrefix string
+	insertIgnoreSuffix string
+	// upsertSuffix returns the insert suffix to update the columns of the rows already inserted
+	// with the given key columns
+	upsertSuffix func(keyColumns []string, updateColumns []string) string
+	// placeholder returns the statement placeholder for the parameter in the position (starting at 1)
+	placeholder func(position int) string
+	// maxPlaceholders is the max number of placeholders allowed in a statement
+	maxPlaceholders int
+	// types are the column types used in the migrations
+	types sqlTypesT
+	// uniqueIndexQuery returns the query listing the name and the columns of the unique indexes
+	// of the table, in a row by index column, with the schema empty for the default one
+	uniqueIndexQuery func(schema string, table string) (query string, args []any)
+	// tableExistsQuery returns the query with a row only when the table exists,
+	// with the schema empty for the default one
+	tableExistsQuery func(schema string, table string) (query string, args []any)
+}
+
+func (m *sqlManagerT) init(ctx context.Context, config v1alpha3.DatabaseT, dialect sqlDialectT) (err error) {
+	m.ctx = ctx
+	m.dialect = dialect
+	m.config = config
+
+	if config.Table == "" {
 		err = fmt.Errorf("database table not provided")
-		return man, err
+		return err
+	}
+
+	m.table, err = m.quoteTable(config.Table)
+	if err != nil {
+		return err
+	}
+	m.tableName = config.Table[strings.LastIndex(config.Table, ".")+1:]
+	m.migrationsTable = m.getMigrationsTable(config.Table)
+
+	// the optional columns are only recorded when they have a name
+	columnValues := []struct {
+		name  string
+		value func(object pools.DatabaseRequestT) any
+	}{
+		{config.Columns.BlobPath, func(o pools.DatabaseRequestT) any { return o.ObjectPath }},
+		{config.Columns.MD5, func(o pools.DatabaseRequestT) any { return o.MD5 }},
+		{config.Columns.BucketName, func(o pools.DatabaseRequestT) any { return o.BucketName }},
+		{config.Columns.ChecksumAlgorithm, func(o pools.DatabaseRequestT) any { return o.ChecksumAlgorithm }},
+		{config.Columns.Checksum, func(o pools.DatabaseRequestT) any { return o.Checksum }},
+		{config.Columns.Size, func(o pools.DatabaseRequestT) any { return o.Size }},
+		{config.Columns.ContentType, func(o pools.DatabaseRequestT) any { return o.ContentType }},
+		{config.Columns.Source, func(o pools.DatabaseRequestT) any { return o.Source }},
+		{config.Columns.TransferredAt, func(o pools.DatabaseRequestT) any { return o.TransferredAt }},
+		{config.Columns.Instance, func(o pools.DatabaseRequestT) any { return o.Instance }},
+		{config.Columns.UpdatedAt, func(o pools.DatabaseRequestT) any { return time.Now().UTC() }},
 	}
+	for _, cv := range columnValues {
+		if cv.name == "" {
+			continue
+		}
+
+		if !columnRegexp.MatchString(cv.name) {
+			err = fmt.Errorf("database column name '%s' is not valid", cv.name)
+			return err
+		}
+
+		m.columns = append(m.columns, columnT{name: cv.name, value: cv.value})
+	}
+
+	if len(m.columns) == 0 {
+		err = fmt.Errorf("database columns not provided")
+		return err
+	}
+
+	switch config.WriteMode {
+	case WriteModeInsertIgnore, "":
+		{
+			m.insertPrefix = m.dialect.insertIgnorePrefix
+			m.insertSuffix = m.dialect.insertIgnoreSuffix
+		}
+	case WriteModeUpsert:
+		{
+			// the object path and bucket identify the rows, the rest of the columns are updated
+			keyColumns := []string{}
+			updateColumns := []string{}
+			for _, column := range m.columns {
+				if column.name == config.Columns.BlobPath || column.name == config.Columns.BucketName {
+					keyColumns = append(keyColumns, m.quoteIdentifier(column.name))
+					continue
+				}
+				updateColumns = append(updateColumns, m.quoteIdentifier(column.name))
+			}
 
-	// Get a database handle.
-	man.Connector, err = mysql.NewConnector(&mysql.Config{
-		User:                 db.Username,
-		Passwd:               db.Password,
-		Net:                  "tcp",
-		Addr:                 fmt.Sprintf("%s:%s", db.Host, db.Port),
-		DBName:               db.Database,
-		AllowNativePasswords: true,
-	})
+			if len(keyColumns) != 2 || len(updateColumns) == 0 {
+				err = fmt.Errorf("database upsert write mode needs the blob path, bucket name and any other column")
+				return err
+			}
 
-	return man, err
+			m.insertPrefix = "INSERT INTO"
+			m.insertSuffix = m.dialect.upsertSuffix(keyColumns, updateColumns)
+		}
+	case WriteModeAppendHistory:
+		{
+			m.insertPrefix = "INSERT INTO"
+		}
+	default:
+		{
+			err = fmt.Errorf("database write mode '%s' not supported", config.WriteMode)
+		}
+	}
+
+	return err
 }
 
-func (m *ManagerT) InsertObjectListIfNotExist(table string, objectList []pools.DatabaseRequestT) (err error) {
-	objectListLen := len(objectList)
-
-	insertQueryClause := fmt.Sprintf("INSERT IGNORE INTO %s (blob_path,md5sum,bucket_name) VALUES ",
-		table,
-	)
-	for index, object := range objectList {
-		// Insert the object into the database.
-		insertQueryClause += fmt.Sprintf("('%s', '%s', '%s')",
-			object.ObjectPath,
-			object.MD5,
-			object.BucketName,
+func (m *sqlManagerT) Close() (err error) {
+	if m.db != nil {
+		err = m.db.Close()
+	}
+	return err
+}
+
+func (m *sqlManagerT) InsertObjectList(objectList []pools.DatabaseRequestT) (err error) {
+	if len(objectList) == 0 {
+		return err
+	}
+
+	columns := []string{}
+	for _, column := range m.columns {
+		columns = append(columns, m.quoteIdentifier(column.name))
+	}
+
+	tx, err := m.db.BeginTx(m.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rowsByStatement := m.dialect.maxPlaceholders / len(columns)
+	for start := 0; start < len(objectList); start += rowsByStatement {
+		end := min(start+rowsByStatement, len(objectList))
+
+		rows := []string{}
+		args := []any{}
+		for _, object := range objectList[start:end] {
+			for _, column := range m.columns {
+				args = append(args, column.value(object))
+			}
+
+			placeholders := []string{}
+			for position := len(args) - len(columns) + 1; position <= len(args); position++ {
+				placeholders = append(placeholders, m.dialect.placeholder(position))
+			}
+			rows = append(rows, "("+strings.Join(placeholders, ",")+")")
+		}
+
+		insertQueryClause := fmt.Sprintf("%s %s (%s) VALUES %s%s;",
+			m.insertPrefix,
+			m.table,
+			strings.Join(columns, ","),
+			strings.Join(rows, ", "),
+			m.insertSuffix,
 		)
-		if index < objectListLen-1 {
-			insertQueryClause += ", "
+
+		_, err = tx.ExecContext(m.ctx, insertQueryClause, args...)
+		if err != nil {
+			return err
 		}
 	}
-	insertQueryClause += ";"
 
-	// Get a database handle.
-	db := sql.OpenDB(m.Connector)
-	defer db.Close()
+	err = tx.Commit()
+	return err
+}
 
-	_, err = db.Exec(insertQueryClause)
+func (m *sqlManagerT) CheckSchema() (err error) {
+	tableColumns, err := m.getTableColumns()
+	if err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for _, column := range m.columns {
+		if !slices.Contains(tableColumns, column.name) {
+			missing = append(missing, column.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		err = fmt.Errorf("database table %s does not have the columns: %s", m.table, strings.Join(missing, ", "))
+		return err
+	}
+
+	// the upserts need the unique index of the objects, only created by the migrations
+	// in this write mode, to update the rows instead of inserting duplicated ones
+	if m.config.WriteMode == WriteModeUpsert {
+		exists, err := m.hasUniqueIndex([]string{m.config.Columns.BucketName, m.config.Columns.BlobPath})
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			err = fmt.Errorf("database table %s does not have a unique index on the columns %s, %s needed by the upsert write mode",
+				m.table, m.config.Columns.BucketName, m.config.Columns.BlobPath)
+			return err
+		}
+	}
 
 	return err
 }
+
+// hasUniqueIndex returns if the table has a unique index on exactly the columns.
+func (m *sqlManagerT) hasUniqueIndex(columns []string) (exists bool, err error) {
+	query, args := m.dialect.uniqueIndexQuery(m.getSchema(), m.tableName)
+	rows, err := m.db.QueryContext(m.ctx, query, args...)
+	if err != nil {
+		return exists, err
+	}
+	defer rows.Close()
+
+	indexColumns := map[string][]string{}
+	for rows.Next() {
+		index, column := "", ""
+		if err = rows.Scan(&index, &column); err != nil {
+			return exists, err
+		}
+		indexColumns[index] = append(indexColumns[index], column)
+	}
+	if err = rows.Err(); err != nil {
+		return exists, err
+	}
+
+	for _, idxColumns := range indexColumns {
+		if len(idxColumns) != len(columns) {
+			continue
+		}
+
+		exists = true
+		for _, column := range columns {
+			if !slices.Contains(idxColumns, column) {
+				exists = false
+				break
+			}
+		}
+		if exists {
+			return exists, err
+		}
+	}
+
+	return exists, err
+}
+
+// getSchema returns the database or schema of the table, empty for the default one.
+func (m *sqlManagerT) getSchema() (schema string) {
+	if index := strings.LastIndex(m.config.Table, "."); index >= 0 {
+		schema = m.config.Table[:index]
+	}
+	return schema
+}
+
+func (m *sqlManagerT) quoteIdentifier(identifier string) string {
+	return m.dialect.quote + identifier + m.dialect.quote
+}
+
+// quoteTable validates the table name and returns it quoted to be used in the queries,
+// as the identifiers can not be passed as statement parameters.
+func (m *sqlManagerT) quoteTable(table string) (quoted string, err error) {
+	if !tableRegexp.MatchString(table) {
+		err = fmt.Errorf("database table name '%s' is not valid", table)
+		return quoted, err
+	}
+
+	identifiers := strings.Split(table, ".")
+	for i := range identifiers {
+		identifiers[i] = m.quoteIdentifier(identifiers[i])
+	}
+
+	quoted = strings.Join(identifiers, ".")
+	return quoted, err
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"bot/api/v1alpha3"
+	"bot/internal/pools"
+)
+
+func TestInsertStatements(t *testing.T) {
+	tests := []struct {
+		name      string
+		dbType    string
+		writeMode string
+
+		expectedPrefix      string
+		expectedSuffix      string
+		expectedPlaceholder string
+	}{
+		{
+			name:                "mysql insert ignore",
+			dbType:              TypeMySQL,
+			writeMode:           WriteModeInsertIgnore,
+			expectedPrefix:      "INSERT IGNORE INTO",
+			expectedPlaceholder: "?",
+		},
+		{
+			name:                "mysql upsert",
+			dbType:              TypeMySQL,
+			writeMode:           WriteModeUpsert,
+			expectedPrefix:      "INSERT INTO",
+			expectedSuffix:      " ON DUPLICATE KEY UPDATE `md5` = VALUES(`md5`)",
+			expectedPlaceholder: "?",
+		},
+		{
+			name:                "postgres insert ignore",
+			dbType:              TypePostgres,
+			writeMode:           WriteModeInsertIgnore,
+			expectedPrefix:      "INSERT INTO",
+			expectedSuffix:      " ON CONFLICT DO NOTHING",
+			expectedPlaceholder: "$3",
+		},
+		{
+			name:                "postgres upsert",
+			dbType:              TypePostgres,
+			writeMode:           WriteModeUpsert,
+			expectedPrefix:      "INSERT INTO",
+			expectedSuffix:      ` ON CONFLICT ("blob_path","bucket_name") DO UPDATE SET "md5" = EXCLUDED."md5"`,
+			expectedPlaceholder: "$3",
+		},
+		{
+			name:                "postgres append history",
+			dbType:              TypePostgres,
+			writeMode:           WriteModeAppendHistory,
+			expectedPrefix:      "INSERT INTO",
+			expectedPlaceholder: "$3",
+		},
+		{
+			name:                "sqlite insert ignore",
+			dbType:              TypeSQLite,
+			writeMode:           WriteModeInsertIgnore,
+			expectedPrefix:      "INSERT OR IGNORE INTO",
+			expectedPlaceholder: "?",
+		},
+		{
+			name:                "sqlite upsert",
+			dbType:              TypeSQLite,
+			writeMode:           WriteModeUpsert,
+			expectedPrefix:      "INSERT INTO",
+			expectedSuffix:      ` ON CONFLICT ("blob_path","bucket_name") DO UPDATE SET "md5" = EXCLUDED."md5"`,
+			expectedPlaceholder: "?",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the databases are not connected until the first query
+			m, err := GetManager(context.Background(), v1alpha3.DatabaseT{
+				Type:      test.dbType,
+				Host:      "localhost",
+				Port:      "5432",
+				Database:  "file::memory:",
+				Table:     "objects",
+				WriteMode: test.writeMode,
+				Columns: v1alpha3.DatabaseColumnsT{
+					BlobPath:   "blob_path",
+					MD5:        "md5",
+					BucketName: "bucket_name",
+				},
+			})
+			if err != nil {
+				t.Fatalf("unable to init manager: %s", err.Error())
+			}
+			defer m.Close()
+
+			var sm *sqlManagerT
+			switch manager := m.(type) {
+			case *MySQLManagerT:
+				sm = &manager.sqlManagerT
+			case *PostgresManagerT:
+				sm = &manager.sqlManagerT
+			case *SQLiteManagerT:
+				sm = &manager.sqlManagerT
+			}
+
+			if sm.insertPrefix != test.expectedPrefix || sm.insertSuffix != test.expectedSuffix {
+				t.Fatalf("insert is '%s ... %s', expected '%s ... %s'",
+					sm.insertPrefix, sm.insertSuffix, test.expectedPrefix, test.expectedSuffix)
+			}
+			if placeholder := sm.dialect.placeholder(3); placeholder != test.expectedPlaceholder {
+				t.Fatalf("placeholder is '%s', expected '%s'", placeholder, test.expectedPlaceholder)
+			}
+		})
+	}
+}
+
+func TestInitInvalidConfig(t *testing.T) {
+	columns := v1alpha3.DatabaseColumnsT{BlobPath: "blob_path", MD5: "md5", BucketName: "bucket_name"}
+
+	tests := []struct {
+		name   string
+		config v1alpha3.DatabaseT
+	}{
+		{
+			name:   "table with injected sql",
+			config: v1alpha3.DatabaseT{Table: "objects; DROP TABLE objects", Columns: columns},
+		},
+		{
+			name:   "column with injected sql",
+			config: v1alpha3.DatabaseT{Table: "objects", Columns: v1alpha3.DatabaseColumnsT{BlobPath: "path`) --"}},
+		},
+		{
+			name:   "upsert without bucket column",
+			config: v1alpha3.DatabaseT{Table: "objects", WriteMode: WriteModeUpsert, Columns: v1alpha3.DatabaseColumnsT{BlobPath: "blob_path", MD5: "md5"}},
+		},
+		{
+			name:   "unknown write mode",
+			config: v1alpha3.DatabaseT{Table: "objects", WriteMode: "replace", Columns: columns},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.Type = TypeSQLite
+			test.config.Database = "file::memory:"
+			if _, err := GetManager(context.Background(), test.config); err == nil {
+				t.Fatalf("expected config error")
+			}
+		})
+	}
+}
+
+func TestInsertObjectList(t *testing.T) {
+	tests := []struct {
+		name      string
+		writeMode string
+
+		expectedRows int
+		expectedMD5  string
+	}{
+		{
+			name:         "insert ignore keeps the first row",
+			writeMode:    WriteModeInsertIgnore,
+			expectedRows: 1,
+			expectedMD5:  "first",
+		},
+		{
+			name:         "upsert updates the row",
+			writeMode:    WriteModeUpsert,
+			expectedRows: 1,
+			expectedMD5:  "second",
+		},
+		{
+			name:         "append history keeps all the rows",
+			writeMode:    WriteModeAppendHistory,
+			expectedRows: 2,
+			expectedMD5:  "second",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := getTestSQLiteManager(t, getTestSQLiteConfig(t, test.writeMode))
+			if _, err := m.Migrate(false); err != nil {
+				t.Fatalf("unable to migrate: %s", err.Error())
+			}
+
+			for _, md5 := range []string{"first", "second"} {
+				err := m.InsertObjectList([]pools.DatabaseRequestT{
+					{BucketName: "bucket", ObjectPath: "object", MD5: md5, Size: 10},
+					{BucketName: "other", ObjectPath: "object", MD5: md5, Size: 10},
+				})
+				if err != nil {
+					t.Fatalf("unable to insert objects: %s", err.Error())
+				}
+			}
+
+			rows := 0
+			md5 := ""
+			err := m.db.QueryRow(`SELECT COUNT(*), MAX(CASE WHEN id = (SELECT MAX(id) FROM "objects" WHERE bucket_name = 'bucket') THEN md5 END) FROM "objects" WHERE bucket_name = 'bucket';`).
+				Scan(&rows, &md5)
+			if err != nil {
+				t.Fatalf("unable to query objects: %s", err.Error())
+			}
+			if rows != test.expectedRows || md5 != test.expectedMD5 {
+				t.Fatalf("table has %d rows with last md5 '%s', expected %d with '%s'", rows, md5, test.expectedRows, test.expectedMD5)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// migrationsTable stores the migrations applied to every catalog table
+	migrationsTable = "bot_schema_migrations"
+)
+
+type MigrationT struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+type MigrationStatusT struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   string
+}
+
+// sqlTypesT are the column types used in the catalog table migrations.
+type sqlTypesT struct {
+	id        string
+	path      string
+	bucket    string
+	text      string
+	bigint    string
+	timestamp string
+}
+
+// migrationDefT defines a catalog table migration, with its statements
+// built from the table columns existing before the migration.
+type migrationDefT struct {
+	version     int
+	description string
+	statements  func(m *sqlManagerT, tableColumns []string) []string
+}
+
+var (
+	migrations = []migrationDefT{
+		{
+			version:     1,
+			description: "create catalog table",
+			statements: func(m *sqlManagerT, tableColumns []string) []string {
+				return []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id %s, %s %s NOT NULL, %s %s, %s %s NOT NULL);",
+					m.table, m.dialect.types.id,
+					m.quoteIdentifier(m.config.Columns.BlobPath), m.dialect.types.path,
+					m.quoteIdentifier(m.config.Columns.MD5), m.dialect.types.text,
+					m.quoteIdentifier(m.config.Columns.BucketName), m.dialect.types.bucket,
+				)}
+			},
+		},
+		{
+			version:     2,
+			description: "create catalog object index",
+			statements: func(m *sqlManagerT, tableColumns []string) []string {
+				// the history of the transfers has many rows for the same object
+				index := "UNIQUE INDEX"
+				if m.config.WriteMode == WriteModeAppendHistory {
+					index = "INDEX"
+				}
+
+				return []string{fmt.Sprintf("CREATE %s %s ON %s (%s, %s);",
+					index, m.quoteIdentifier(m.tableName+"_object_idx"), m.table,
+					m.quoteIdentifier(m.config.Columns.BucketName),
+					m.quoteIdentifier(m.config.Columns.BlobPath),
+				)}
+			},
+		},
+		{
+			version:     3,
+			description: "add catalog checksum columns",
+			statements: func(m *sqlManagerT, tableColumns []string) []string {
+				return m.addColumnStatements(tableColumns, [][2]string{
+					{getColumnName(m.config.Columns.ChecksumAlgorithm, "checksum_algorithm"), m.dialect.types.text},
+					{getColumnName(m.config.Columns.Checksum, "checksum"), m.dialect.types.text},
+				})
+			},
+		},
+		{
+			version:     4,
+			description: "add catalog object attribute columns",
+			statements: func(m *sqlManagerT, tableColumns []string) []string {
+				return m.addColumnStatements(tableColumns, [][2]string{
+					{getColumnName(m.config.Columns.Size, "size"), m.dialect.types.bigint},
+					{getColumnName(m.config.Columns.ContentType, "content_type"), m.dialect.types.text},
+					{getColumnName(m.config.Columns.Source, "source_name"), m.dialect.types.text},
+					{getColumnName(m.config.Columns.TransferredAt, "transferred_at"), m.dialect.types.timestamp},
+					{getColumnName(m.config.Columns.Instance, "instance"), m.dialect.types.text},
+					{getColumnName(m.config.Columns.UpdatedAt, "updated_at"), m.dialect.types.timestamp},
+				})
+			},
+		},
+	}
+)
+
+// Migrate applies the catalog table migrations not applied yet and returns them.
+// In dry run mode, the migrations are only returned.
+func (m *sqlManagerT) Migrate(dryRun bool) (applied []MigrationT, err error) {
+	statusList, err := m.GetMigrationStatus()
+	if err != nil {
+		return applied, err
+	}
+
+	// the table does not exist before the first migration
+	tableColumns := []string{}
+	exists, err := m.tableExists(m.tableName)
+	if err != nil {
+		return applied, err
+	}
+	if exists {
+		tableColumns, err = m.getTableColumns()
+		if err != nil {
+			return applied, err
+		}
+	}
+
+	// the dry runs do not change the database
+	if !dryRun {
+		if err = m.createMigrationsTable(); err != nil {
+			return applied, err
+		}
+	}
+
+	for i, status := range statusList {
+		if status.Applied {
+			continue
+		}
+
+		migration := MigrationT{
+			Version:     status.Version,
+			Description: status.Description,
+			Statements:  migrations[i].statements(m, tableColumns),
+		}
+		applied = append(applied, migration)
+
+		if dryRun {
+			continue
+		}
+
+		for _, statement := range migration.Statements {
+			if _, err = m.db.ExecContext(m.ctx, statement); err != nil {
+				err = fmt.Errorf("unable to apply migration %d: %w", migration.Version, err)
+				return applied, err
+			}
+		}
+
+		_, err = m.db.ExecContext(m.ctx, fmt.Sprintf("INSERT INTO %s (table_name, version, description, applied_at) VALUES (%s, %s, %s, CURRENT_TIMESTAMP);",
+			m.migrationsTable, m.dialect.placeholder(1), m.dialect.placeholder(2), m.dialect.placeholder(3)),
+			m.tableName, migration.Version, migration.Description,
+		)
+		if err != nil {
+			err = fmt.Errorf("unable to record migration %d: %w", migration.Version, err)
+			return applied, err
+		}
+
+		tableColumns, err = m.getTableColumns()
+		if err != nil {
+			return applied, err
+		}
+	}
+
+	return applied, err
+}
+
+// GetMigrationStatus returns all the catalog table migrations with their applied state.
+// It does not change the database, none is applied when the migrations table does not exist.
+func (m *sqlManagerT) GetMigrationStatus() (statusList []MigrationStatusT, err error) {
+	exists, err := m.tableExists(migrationsTable)
+	if err != nil {
+		return statusList, err
+	}
+
+	if !exists {
+		for _, migration := range migrations {
+			statusList = append(statusList, MigrationStatusT{
+				Version:     migration.version,
+				Description: migration.description,
+			})
+		}
+		return statusList, err
+	}
+
+	rows, err := m.db.QueryContext(m.ctx, fmt.Sprintf("SELECT version, applied_at FROM %s WHERE table_name = %s;",
+		m.migrationsTable, m.dialect.placeholder(1)),
+		m.tableName,
+	)
+	if err != nil {
+		return statusList, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]string{}
+	for rows.Next() {
+		version := 0
+		at := ""
+		if err = rows.Scan(&version, &at); err != nil {
+			return statusList, err
+		}
+		appliedAt[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return statusList, err
+	}
+
+	for _, migration := range migrations {
+		at, applied := appliedAt[migration.version]
+		statusList = append(statusList, MigrationStatusT{
+			Version:     migration.version,
+			Description: migration.description,
+			Applied:     applied,
+			AppliedAt:   at,
+		})
+	}
+
+	return statusList, err
+}
+
+// addColumnStatements returns the statements to add the columns not existing in the table,
+// one by statement as SQLite does not allow more.
+func (m *sqlManagerT) addColumnStatements(tableColumns []string, columns [][2]string) (statements []string) {
+	for _, column := range columns {
+		if slices.Contains(tableColumns, column[0]) {
+			continue
+		}
+
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;",
+			m.table, m.quoteIdentifier(column[0]), column[1],
+		))
+	}
+
+	return statements
+}
+
+// createMigrationsTable creates the migrations table when it does not exist.
+func (m *sqlManagerT) createMigrationsTable() (err error) {
+	_, err = m.db.ExecContext(m.ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (table_name %s NOT NULL, version INTEGER NOT NULL, description %s NOT NULL, applied_at %s NOT NULL, PRIMARY KEY (table_name, version));",
+		m.migrationsTable, m.dialect.types.bucket, m.dialect.types.text, m.dialect.types.timestamp,
+	))
+	return err
+}
+
+// tableExists returns if the unquoted table exists in the schema of the catalog table,
+// looking it up in the database catalog to tell a missing table from a failed query.
+func (m *sqlManagerT) tableExists(table string) (exists bool, err error) {
+	query, args := m.dialect.tableExistsQuery(m.getSchema(), table)
+	rows, err := m.db.QueryContext(m.ctx, query, args...)
+	if err != nil {
+		return exists, err
+	}
+	defer rows.Close()
+
+	exists = rows.Next()
+	err = rows.Err()
+
+	return exists, err
+}
+
+func (m *sqlManagerT) getTableColumns() (tableColumns []string, err error) {
+	rows, err := m.db.QueryContext(m.ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0;", m.table))
+	if err != nil {
+		return tableColumns, err
+	}
+	defer rows.Close()
+
+	tableColumns, err = rows.Columns()
+	return tableColumns, err
+}
+
+func getColumnName(name string, defaultName string) string {
+	if name == "" {
+		return defaultName
+	}
+	return name
+}
+
+// getMigrationsTable returns the quoted migrations table in the same database
+// or schema of the catalog table.
+func (m *sqlManagerT) getMigrationsTable(table string) string {
+	identifiers := strings.Split(table, ".")
+	identifiers[len(identifiers)-1] = migrationsTable
+
+	for i := range identifiers {
+		identifiers[i] = m.quoteIdentifier(identifiers[i])
+	}
+	return strings.Join(identifiers, ".")
+}
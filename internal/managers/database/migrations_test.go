@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"bot/api/v1alpha3"
+)
+
+// getTestSQLiteConfig returns the config of a catalog table in a new SQLite database.
+func getTestSQLiteConfig(t *testing.T, writeMode string) v1alpha3.DatabaseT {
+	return v1alpha3.DatabaseT{
+		Type:      TypeSQLite,
+		Database:  filepath.Join(t.TempDir(), "catalog.db"),
+		Table:     "objects",
+		WriteMode: writeMode,
+		Columns: v1alpha3.DatabaseColumnsT{
+			BlobPath:   "blob_path",
+			MD5:        "md5",
+			BucketName: "bucket_name",
+			Size:       "size",
+			UpdatedAt:  "updated_at",
+		},
+	}
+}
+
+func getTestSQLiteManager(t *testing.T, config v1alpha3.DatabaseT) *SQLiteManagerT {
+	t.Helper()
+
+	m := &SQLiteManagerT{}
+	if err := m.Init(context.Background(), config); err != nil {
+		t.Fatalf("unable to init manager: %s", err.Error())
+	}
+	t.Cleanup(func() { m.Close() })
+
+	return m
+}
+
+func TestMigrate(t *testing.T) {
+	for _, writeMode := range []string{WriteModeInsertIgnore, WriteModeUpsert, WriteModeAppendHistory} {
+		t.Run(writeMode, func(t *testing.T) {
+			m := getTestSQLiteManager(t, getTestSQLiteConfig(t, writeMode))
+
+			// the dry runs and the status do not create the tables
+			for _, dryRun := range []bool{true, false} {
+				statusList, err := m.GetMigrationStatus()
+				if err != nil {
+					t.Fatalf("unable to get migration status: %s", err.Error())
+				}
+				if len(statusList) != len(migrations) {
+					t.Fatalf("status has %d migrations, expected %d", len(statusList), len(migrations))
+				}
+				for _, status := range statusList {
+					if status.Applied {
+						t.Fatalf("migration %d applied before migrating", status.Version)
+					}
+				}
+
+				applied, err := m.Migrate(dryRun)
+				if err != nil {
+					t.Fatalf("unable to migrate with dry run %t: %s", dryRun, err.Error())
+				}
+				if len(applied) != len(migrations) {
+					t.Fatalf("%d migrations applied with dry run %t, expected %d", len(applied), dryRun, len(migrations))
+				}
+
+				exists, err := m.tableExists(m.tableName)
+				if err != nil {
+					t.Fatalf("unable to check table: %s", err.Error())
+				}
+				if exists == dryRun {
+					t.Fatalf("table exists is %t after migrating with dry run %t", exists, dryRun)
+				}
+			}
+
+			statusList, err := m.GetMigrationStatus()
+			if err != nil {
+				t.Fatalf("unable to get migration status: %s", err.Error())
+			}
+			for _, status := range statusList {
+				if !status.Applied || status.AppliedAt == "" {
+					t.Fatalf("migration %d not applied: %+v", status.Version, status)
+				}
+			}
+
+			applied, err := m.Migrate(false)
+			if err != nil || len(applied) != 0 {
+				t.Fatalf("migrated %d migrations again (%v)", len(applied), err)
+			}
+
+			if err = m.CheckSchema(); err != nil {
+				t.Fatalf("migrated schema not valid: %s", err.Error())
+			}
+
+			// only the upsert write mode needs the unique index
+			unique, err := m.hasUniqueIndex([]string{"bucket_name", "blob_path"})
+			if err != nil {
+				t.Fatalf("unable to check unique index: %s", err.Error())
+			}
+			if unique == (writeMode == WriteModeAppendHistory) {
+				t.Fatalf("unique index is %t in write mode %s", unique, writeMode)
+			}
+		})
+	}
+}
+
+func TestTableExists(t *testing.T) {
+	m := getTestSQLiteManager(t, getTestSQLiteConfig(t, WriteModeInsertIgnore))
+	if _, err := m.db.Exec(`CREATE TABLE "objects" ("blob_path" TEXT);`); err != nil {
+		t.Fatalf("unable to create table: %s", err.Error())
+	}
+
+	tests := []struct {
+		name  string
+		table string
+
+		expected bool
+	}{
+		{name: "existing table", table: "objects", expected: true},
+		{name: "missing table", table: "missing", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exists, err := m.tableExists(test.table)
+			if err != nil {
+				t.Fatalf("unable to check table: %s", err.Error())
+			}
+			if exists != test.expected {
+				t.Fatalf("table exists is %t, expected %t", exists, test.expected)
+			}
+		})
+	}
+
+	// the failed queries are not a missing table
+	m.Close()
+	if _, err := m.tableExists("objects"); err == nil {
+		t.Fatalf("expected error with the database closed")
+	}
+	if _, err := m.GetMigrationStatus(); err == nil {
+		t.Fatalf("expected migration status error with the database closed")
+	}
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLManagerT struct {
+	sqlManagerT
+}
+
+func (m *MySQLManagerT) Init(ctx context.Context, config v1alpha3.DatabaseT) (err error) {
+	err = m.init(ctx, config, sqlDialectT{
+		quote:              "`",
+		insertIgnorePrefix: "INSERT IGNORE INTO",
+		upsertSuffix: func(keyColumns []string, updateColumns []string) string {
+			updates := []string{}
+			for _, column := range updateColumns {
+				updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", column, column))
+			}
+			return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+		},
+		placeholder: func(position int) string {
+			return "?"
+		},
+		maxPlaceholders: 65535,
+		uniqueIndexQuery: func(schema string, table string) (string, []any) {
+			return "SELECT INDEX_NAME, COLUMN_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND NON_UNIQUE = 0;",
+				[]any{schema, table}
+		},
+		tableExistsQuery: func(schema string, table string) (string, []any) {
+			return "SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?;",
+				[]any{schema, table}
+		},
+		types: sqlTypesT{
+			id: "BIGINT AUTO_INCREMENT PRIMARY KEY",
+			// binary collation as the object paths are case sensitive,
+			// and the length keeps the object index under the max key length
+			path:      "VARCHAR(700) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin",
+			bucket:    "VARCHAR(255) CHARACTER SET ascii",
+			text:      "VARCHAR(255)",
+			bigint:    "BIGINT",
+			timestamp: "DATETIME(6)",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	connector, err := mysql.NewConnector(&mysql.Config{
+		User:                 config.Username,
+		Passwd:               config.Password,
+		Net:                  "tcp",
+		Addr:                 fmt.Sprintf("%s:%s", config.Host, config.Port),
+		DBName:               config.Database,
+		AllowNativePasswords: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the handle keeps a pool of connections for all the inserts
+	m.db = sql.OpenDB(connector)
+
+	return err
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+type PostgresManagerT struct {
+	sqlManagerT
+}
+
+func (m *PostgresManagerT) Init(ctx context.Context, config v1alpha3.DatabaseT) (err error) {
+	err = m.init(ctx, config, sqlDialectT{
+		quote:              `"`,
+		insertIgnorePrefix: "INSERT INTO",
+		insertIgnoreSuffix: " ON CONFLICT DO NOTHING",
+		upsertSuffix:       upsertOnConflictSuffix,
+		placeholder: func(position int) string {
+			return fmt.Sprintf("$%d", position)
+		},
+		maxPlaceholders: 65535,
+		uniqueIndexQuery: func(schema string, table string) (string, []any) {
+			relation := `"` + table + `"`
+			if schema != "" {
+				relation = `"` + schema + `".` + relation
+			}
+			return "SELECT c.relname, a.attname FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey) WHERE i.indrelid = to_regclass($1::text) AND i.indisunique AND i.indpred IS NULL;",
+				[]any{relation}
+		},
+		tableExistsQuery: func(schema string, table string) (string, []any) {
+			relation := `"` + table + `"`
+			if schema != "" {
+				relation = `"` + schema + `".` + relation
+			}
+			return "SELECT 1 WHERE to_regclass($1::text) IS NOT NULL;",
+				[]any{relation}
+		},
+		types: sqlTypesT{
+			id:        "BIGSERIAL PRIMARY KEY",
+			path:      "TEXT",
+			bucket:    "VARCHAR(255)",
+			text:      "VARCHAR(255)",
+			bigint:    "BIGINT",
+			timestamp: "TIMESTAMPTZ",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(config.Username, config.Password),
+		Host:   net.JoinHostPort(config.Host, config.Port),
+		Path:   config.Database,
+	}
+
+	// the handle keeps a pool of connections for all the inserts
+	m.db, err = sql.Open("pgx", dsn.String())
+
+	return err
+}
+
+// upsertOnConflictSuffix returns the upsert clause shared by PostgreSQL and SQLite.
+func upsertOnConflictSuffix(keyColumns []string, updateColumns []string) string {
+	updates := []string{}
+	for _, column := range updateColumns {
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keyColumns, ","), strings.Join(updates, ", "))
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"database/sql"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+type SQLiteManagerT struct {
+	sqlManagerT
+}
+
+// Init opens the SQLite database file set in the database option of the config.
+func (m *SQLiteManagerT) Init(ctx context.Context, config v1alpha3.DatabaseT) (err error) {
+	err = m.init(ctx, config, sqlDialectT{
+		quote:              `"`,
+		insertIgnorePrefix: "INSERT OR IGNORE INTO",
+		upsertSuffix:       upsertOnConflictSuffix,
+		placeholder: func(position int) string {
+			return "?"
+		},
+		maxPlaceholders: 32766,
+		uniqueIndexQuery: func(schema string, table string) (string, []any) {
+			if schema == "" {
+				schema = "main"
+			}
+			return `SELECT il.name, ii.name FROM pragma_index_list(?, ?) il JOIN pragma_index_info(il.name, ?) ii WHERE il."unique" = 1 AND il.partial = 0;`,
+				[]any{table, schema, schema}
+		},
+		tableExistsQuery: func(schema string, table string) (string, []any) {
+			if schema == "" {
+				schema = "main"
+			}
+			return `SELECT 1 FROM "` + schema + `".sqlite_master WHERE type = 'table' AND name = ?;`,
+				[]any{table}
+		},
+		types: sqlTypesT{
+			id:        "INTEGER PRIMARY KEY AUTOINCREMENT",
+			path:      "TEXT",
+			bucket:    "TEXT",
+			text:      "TEXT",
+			bigint:    "INTEGER",
+			timestamp: "TIMESTAMP",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// the times are stored in the sqlite format instead of the go one
+	dsn := config.Database + "?_time_format=sqlite"
+	if strings.Contains(config.Database, "?") {
+		dsn = config.Database + "&_time_format=sqlite"
+	}
+
+	m.db, err = sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+
+	// sqlite only allows one writer at the same time
+	m.db.SetMaxOpenConns(1)
+
+	return err
+}
@@ -0,0 +1,51 @@
+package discovery
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+)
+
+// DNSManagerT discovers the instances looking up the proxy host,
+// usually a headless service resolving to the ready instances.
+type DNSManagerT struct {
+	ctx      context.Context
+	host     string
+	resolver *net.Resolver
+}
+
+func (m *DNSManagerT) Init(ctx context.Context, config v1alpha3.HashRingWorkerConfigT) (err error) {
+	m.ctx = ctx
+	m.host = config.Proxy
+	m.resolver = net.DefaultResolver
+
+	if m.host == "" {
+		err = fmt.Errorf("hashring proxy host not provided")
+	}
+	return err
+}
+
+func (m *DNSManagerT) GetAddresses() (addresses []string, err error) {
+	addresses, err = m.resolver.LookupHost(m.ctx, m.host)
+	return addresses, err
+}
+
+func (m *DNSManagerT) HasAddress(address string) (found bool, err error) {
+	addresses, err := m.GetAddresses()
+	if err != nil {
+		return found, err
+	}
+
+	found = slices.Contains(addresses, address)
+	return found, err
+}
+
+func (m *DNSManagerT) Events() <-chan struct{} {
+	return nil
+}
+
+func (m *DNSManagerT) Close() (err error) {
+	return err
+}
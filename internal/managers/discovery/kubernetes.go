@@ -0,0 +1,169 @@
+package discovery
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// serviceAccountNamespaceFile stores the pod namespace in the cluster
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	kubernetesSyncTimeout = 30 * time.Second
+)
+
+// KubernetesManagerT discovers the instances watching the EndpointSlices of the service,
+// so the readiness changes are notified as soon as the API server publishes them.
+type KubernetesManagerT struct {
+	cancel  context.CancelFunc
+	factory informers.SharedInformerFactory
+	lister  discoverylisters.EndpointSliceNamespaceLister
+	events  chan struct{}
+}
+
+func (m *KubernetesManagerT) Init(ctx context.Context, config v1alpha3.HashRingWorkerConfigT) (err error) {
+	kconfig := config.Discovery.Kubernetes
+
+	if kconfig.Service == "" {
+		err = fmt.Errorf("hashring kubernetes discovery service not provided")
+		return err
+	}
+
+	namespace := kconfig.Namespace
+	if namespace == "" {
+		namespaceBytes, err := os.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return fmt.Errorf("hashring kubernetes discovery namespace not provided: %w", err)
+		}
+		namespace = strings.TrimSpace(string(namespaceBytes))
+	}
+
+	var restConfig *rest.Config
+	if kconfig.Kubeconfig != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", kconfig.Kubeconfig)
+	} else {
+		restConfig, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return err
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	// the EndpointSlices of a service are labeled with its name
+	selector := labels.Set{discoveryv1.LabelServiceName: kconfig.Service}.String()
+	m.factory = informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = selector
+		}),
+	)
+
+	informer := m.factory.Discovery().V1().EndpointSlices()
+	m.lister = informer.Lister().EndpointSlices(namespace)
+	m.events = make(chan struct{}, 1)
+
+	_, err = informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { m.notify() },
+		UpdateFunc: func(oldObj, newObj any) { m.notify() },
+		DeleteFunc: func(obj any) { m.notify() },
+	})
+	if err != nil {
+		return err
+	}
+
+	var watchCtx context.Context
+	watchCtx, m.cancel = context.WithCancel(ctx)
+	m.factory.Start(watchCtx.Done())
+
+	syncCtx, syncCancel := context.WithTimeout(watchCtx, kubernetesSyncTimeout)
+	defer syncCancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.Informer().HasSynced) {
+		m.Close()
+		err = fmt.Errorf("unable to list '%s/%s' service endpointslices", namespace, kconfig.Service)
+	}
+
+	return err
+}
+
+func (m *KubernetesManagerT) GetAddresses() (addresses []string, err error) {
+	return m.getAddresses(true)
+}
+
+func (m *KubernetesManagerT) HasAddress(address string) (found bool, err error) {
+	addresses, err := m.getAddresses(false)
+	if err != nil {
+		return found, err
+	}
+
+	found = slices.Contains(addresses, address)
+	return found, err
+}
+
+func (m *KubernetesManagerT) Events() <-chan struct{} {
+	return m.events
+}
+
+func (m *KubernetesManagerT) Close() (err error) {
+	if m.cancel != nil {
+		m.cancel()
+		m.factory.Shutdown()
+	}
+	return err
+}
+
+// getAddresses returns the sorted addresses of the service endpoints, only the ready ones
+// when requested. The first address of every endpoint identifies it.
+func (m *KubernetesManagerT) getAddresses(onlyReady bool) (addresses []string, err error) {
+	sliceList, err := m.lister.List(labels.Everything())
+	if err != nil {
+		return addresses, err
+	}
+
+	for _, slice := range sliceList {
+		for _, endpoint := range slice.Endpoints {
+			if len(endpoint.Addresses) == 0 {
+				continue
+			}
+
+			// a nil ready condition must be interpreted as ready
+			if onlyReady && endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+
+			if !slices.Contains(addresses, endpoint.Addresses[0]) {
+				addresses = append(addresses, endpoint.Addresses[0])
+			}
+		}
+	}
+	slices.Sort(addresses)
+
+	return addresses, err
+}
+
+// notify signals a change in the endpoints without blocking,
+// as a pending notification already covers it.
+func (m *KubernetesManagerT) notify() {
+	select {
+	case m.events <- struct{}{}:
+	default:
+	}
+}
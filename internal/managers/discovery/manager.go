@@ -0,0 +1,53 @@
+package discovery
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"fmt"
+)
+
+const (
+	TypeDNS        = "dns"
+	TypeStatic     = "static"
+	TypeKubernetes = "kubernetes"
+	// TypeGossip maintains the membership with the gossip protocol in the gossip package,
+	// without a discovery manager
+	TypeGossip = "gossip"
+)
+
+type DiscoveryManagerI interface {
+	Init(ctx context.Context, config v1alpha3.HashRingWorkerConfigT) error
+	// GetAddresses returns the addresses of the instances ready to receive requests.
+	GetAddresses() ([]string, error)
+	// HasAddress returns if the address belongs to a known instance,
+	// even when it is not ready to receive requests yet.
+	HasAddress(address string) (bool, error)
+	// Events returns a channel notified when the instances change,
+	// or nil when the addresses must be polled.
+	Events() <-chan struct{}
+	Close() error
+}
+
+func GetManager(ctx context.Context, config v1alpha3.HashRingWorkerConfigT) (m DiscoveryManagerI, err error) {
+	switch config.Discovery.Type {
+	case TypeDNS:
+		{
+			m = &DNSManagerT{}
+		}
+	case TypeStatic:
+		{
+			m = &StaticManagerT{}
+		}
+	case TypeKubernetes:
+		{
+			m = &KubernetesManagerT{}
+		}
+	default:
+		{
+			err = fmt.Errorf("hashring discovery type '%s' not supported", config.Discovery.Type)
+			return m, err
+		}
+	}
+	err = m.Init(ctx, config)
+	return m, err
+}
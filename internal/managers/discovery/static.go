@@ -0,0 +1,39 @@
+package discovery
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// StaticManagerT discovers the instances from a fixed address list,
+// leaving the unreachable ones to the hashring health checks.
+type StaticManagerT struct {
+	addresses []string
+}
+
+func (m *StaticManagerT) Init(ctx context.Context, config v1alpha3.HashRingWorkerConfigT) (err error) {
+	m.addresses = slices.Clone(config.Discovery.Static.Addresses)
+
+	if len(m.addresses) == 0 {
+		err = fmt.Errorf("hashring static discovery addresses not provided")
+	}
+	return err
+}
+
+func (m *StaticManagerT) GetAddresses() (addresses []string, err error) {
+	return slices.Clone(m.addresses), err
+}
+
+func (m *StaticManagerT) HasAddress(address string) (found bool, err error) {
+	return slices.Contains(m.addresses, address), err
+}
+
+func (m *StaticManagerT) Events() <-chan struct{} {
+	return nil
+}
+
+func (m *StaticManagerT) Close() (err error) {
+	return err
+}
@@ -0,0 +1,156 @@
+package gossip
+
+import (
+	"bot/api/v1alpha3"
+	"bot/internal/pools"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+)
+
+const (
+	EventJoin   = "join"
+	EventLeave  = "leave"
+	EventUpdate = "update"
+
+	// eventsBufferLength is the number of events buffered before blocking the gossip protocol
+	eventsBufferLength = 1024
+	leaveTimeout       = 5 * time.Second
+)
+
+// MemberEventT is a membership change of a cluster member, with the server
+// built from the metadata gossiped by the member.
+type MemberEventT struct {
+	Type   string
+	Server pools.ServerT
+}
+
+// GossipManagerT maintains the cluster membership with the SWIM gossip protocol,
+// detecting the failed members and notifying the membership changes.
+type GossipManagerT struct {
+	ctx   context.Context
+	list  *memberlist.Memberlist
+	seeds []string
+
+	meta   []byte
+	events chan MemberEventT
+}
+
+// Init starts the gossip protocol for the server, carrying its name, address, port and version
+// as member metadata. The memberlist logs are written in the log output.
+func (m *GossipManagerT) Init(ctx context.Context, config v1alpha3.HashRingWorkerConfigT, server pools.ServerT, logOutput io.Writer) (err error) {
+	m.ctx = ctx
+	m.seeds = config.Discovery.Gossip.Seeds
+	m.events = make(chan MemberEventT, eventsBufferLength)
+
+	m.meta, err = json.Marshal(server)
+	if err != nil {
+		return err
+	}
+
+	gconfig := config.Discovery.Gossip
+	mconfig := memberlist.DefaultLANConfig()
+	mconfig.Name = server.Name
+	mconfig.BindAddr = gconfig.BindAddress
+	mconfig.BindPort = gconfig.Port
+	mconfig.AdvertiseAddr = server.Address
+	mconfig.AdvertisePort = gconfig.Port
+	mconfig.ProbeInterval = gconfig.ProbeInterval
+	mconfig.ProbeTimeout = gconfig.ProbeTimeout
+	mconfig.SuspicionMult = gconfig.SuspicionMultiplier
+	mconfig.GossipInterval = gconfig.GossipInterval
+	mconfig.LogOutput = logOutput
+	mconfig.Delegate = m
+	mconfig.Events = m
+
+	if gconfig.SecretKey != "" {
+		mconfig.SecretKey, err = base64.StdEncoding.DecodeString(gconfig.SecretKey)
+		if err != nil {
+			err = fmt.Errorf("unable to decode gossip secret key: %w", err)
+			return err
+		}
+	}
+
+	m.list, err = memberlist.Create(mconfig)
+	return err
+}
+
+// Join contacts the seeds to join the cluster and returns the number of seeds contacted.
+func (m *GossipManagerT) Join() (contacted int, err error) {
+	if len(m.seeds) == 0 {
+		return contacted, err
+	}
+
+	contacted, err = m.list.Join(m.seeds)
+	return contacted, err
+}
+
+// IsAlone returns if this instance is the only known member.
+func (m *GossipManagerT) IsAlone() bool {
+	return m.list.NumMembers() <= 1
+}
+
+func (m *GossipManagerT) Events() <-chan MemberEventT {
+	return m.events
+}
+
+// Close leaves the cluster, notifying it to the other members, and stops the gossip protocol.
+func (m *GossipManagerT) Close() (err error) {
+	if m.list == nil {
+		return err
+	}
+
+	err = m.list.Leave(leaveTimeout)
+	if shutdownErr := m.list.Shutdown(); err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+// MEMBERLIST DELEGATE FUNCTIONS
+
+func (m *GossipManagerT) NodeMeta(limit int) []byte {
+	return m.meta
+}
+
+func (m *GossipManagerT) NotifyMsg([]byte) {}
+
+func (m *GossipManagerT) GetBroadcasts(overhead, limit int) [][]byte {
+	return nil
+}
+
+func (m *GossipManagerT) LocalState(join bool) []byte {
+	return nil
+}
+
+func (m *GossipManagerT) MergeRemoteState(buf []byte, join bool) {}
+
+// MEMBERLIST EVENT DELEGATE FUNCTIONS
+
+func (m *GossipManagerT) NotifyJoin(node *memberlist.Node) {
+	m.notify(EventJoin, node)
+}
+
+func (m *GossipManagerT) NotifyLeave(node *memberlist.Node) {
+	m.notify(EventLeave, node)
+}
+
+func (m *GossipManagerT) NotifyUpdate(node *memberlist.Node) {
+	m.notify(EventUpdate, node)
+}
+
+// notify sends the member event, ignoring the members without valid metadata,
+// as they are not bot instances.
+func (m *GossipManagerT) notify(eventType string, node *memberlist.Node) {
+	server := pools.ServerT{}
+	if err := json.Unmarshal(node.Meta, &server); err != nil || server.Name != node.Name {
+		return
+	}
+
+	m.events <- MemberEventT{Type: eventType, Server: server}
+}
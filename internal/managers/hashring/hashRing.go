@@ -1,100 +1,187 @@
 package hashring
 
 import (
+	"fmt"
 	"hash/crc32"
+	"hash/fnv"
 	"slices"
-	"sort"
-	"strconv"
+	"strings"
 	"sync"
+
+	"github.com/cespare/xxhash/v2"
 )
 
-type HashRingT struct {
-	mu            sync.Mutex
-	nodes         []NodeT
-	vnodesPerNode int
+const (
+	StrategyRing       = "ring"
+	StrategyRendezvous = "rendezvous"
+	StrategyJump       = "jump"
+	StrategyMaglev     = "maglev"
+
+	HashFunctionCRC32  = "crc32"
+	HashFunctionFNV    = "fnv"
+	HashFunctionXXHash = "xxhash"
+)
+
+var (
+	Strategies    = []string{StrategyRing, StrategyRendezvous, StrategyJump, StrategyMaglev}
+	HashFunctions = []string{HashFunctionCRC32, HashFunctionFNV, HashFunctionXXHash}
+)
+
+// StrategyI assigns the keys to the nodes. The strategies are not safe for concurrent use,
+// the hashring serializes the node changes with the lookups.
+type StrategyI interface {
+	// SetNodes rebuilds the strategy state for the nodes, sorted by name.
+	SetNodes(nodes []NodeT)
+	// GetNode returns the node name assigned to the key, empty when there are no nodes.
+	GetNode(key string) string
 }
 
+// HashFuncT returns the 64 bits hash of the data.
+type HashFuncT func(data []byte) uint64
+
+// NodeT is a hashring node, with a weight relative to the other nodes
+// (the weights below 1 are handled as 1).
 type NodeT struct {
-	hash int
-	name string
+	Name   string
+	Weight int
+}
+
+// HashRingT stores the hashring nodes and assigns the keys to them with its strategy.
+type HashRingT struct {
+	mu       sync.RWMutex
+	strategy StrategyI
+	nodes    map[string]NodeT
 }
 
-func NewHashRing(vnodesPerNode int) *HashRingT {
+func NewHashRing(strategy StrategyI) *HashRingT {
 	return &HashRingT{
-		vnodesPerNode: vnodesPerNode,
+		strategy: strategy,
+		nodes:    map[string]NodeT{},
 	}
 }
 
-func (h *HashRingT) AddNodes(nodeNames []string) {
-	h.mu.Lock()
-	for _, nodeName := range nodeNames {
-		for i := 0; i < h.vnodesPerNode; i++ {
-			vnode := nodeName + "#" + strconv.Itoa(i)
-			hash := int(crc32.ChecksumIEEE([]byte(vnode)))
-			h.nodes = append(h.nodes, NodeT{hash: hash, name: nodeName})
-		}
-
-		sort.Slice(h.nodes, func(i, j int) bool {
-			return h.nodes[i].hash < h.nodes[j].hash
-		})
+// GetStrategy returns the strategy with the hash function, using the vnodes by node
+// in the ring strategy.
+func GetStrategy(strategy string, hashFunction string, vnodesPerNode int) (s StrategyI, err error) {
+	hash, err := GetHashFunc(hashFunction)
+	if err != nil {
+		return s, err
 	}
-	h.mu.Unlock()
-}
 
-func (hr *HashRingT) InHashRing(nodeName string) (result bool) {
-	hr.mu.Lock()
-	for _, node := range hr.nodes {
-		if node.name == nodeName {
-			result = true
-			break
+	switch strategy {
+	case StrategyRing:
+		{
+			s = NewRingStrategy(hash, vnodesPerNode)
+		}
+	case StrategyRendezvous:
+		{
+			s = NewRendezvousStrategy(hash)
+		}
+	case StrategyJump:
+		{
+			s = NewJumpStrategy(hash)
+		}
+	case StrategyMaglev:
+		{
+			s = NewMaglevStrategy(hash, MaglevTableSize)
+		}
+	default:
+		{
+			err = fmt.Errorf("hashring strategy '%s' not supported", strategy)
 		}
 	}
-	hr.mu.Unlock()
 
-	return result
+	return s, err
 }
 
-func (hr *HashRingT) GetNodes() (result []NodeT) {
-	hr.mu.Lock()
-	result = hr.nodes
-	hr.mu.Unlock()
+func GetHashFunc(hashFunction string) (hash HashFuncT, err error) {
+	switch hashFunction {
+	case HashFunctionCRC32:
+		{
+			hash = func(data []byte) uint64 {
+				return uint64(crc32.ChecksumIEEE(data))
+			}
+		}
+	case HashFunctionFNV:
+		{
+			hash = func(data []byte) uint64 {
+				h := fnv.New64a()
+				h.Write(data)
+				return h.Sum64()
+			}
+		}
+	case HashFunctionXXHash:
+		{
+			hash = xxhash.Sum64
+		}
+	default:
+		{
+			err = fmt.Errorf("hashring hash function '%s' not supported", hashFunction)
+		}
+	}
 
-	return result
+	return hash, err
 }
 
-func (h *HashRingT) RemoveNodes(nodes []string) {
+// AddNodes adds the nodes in the hashring, replacing the ones with the same name,
+// and rebuilds the strategy once for all of them.
+func (h *HashRingT) AddNodes(nodes []NodeT) {
 	h.mu.Lock()
-	var newNodes []NodeT
+	defer h.mu.Unlock()
 
-	for _, node := range h.nodes {
-		if !slices.Contains(nodes, node.name) {
-			newNodes = append(newNodes, node)
-		}
+	for _, node := range nodes {
+		h.nodes[node.Name] = node
 	}
-
-	h.nodes = newNodes
-	h.mu.Unlock()
+	h.strategy.SetNodes(h.getSortedNodes())
 }
 
-func (h *HashRingT) GetNode(key string) (node string) {
+func (h *HashRingT) RemoveNodes(nodeNames []string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if len(h.nodes) == 0 {
-		return ""
+	for _, nodeName := range nodeNames {
+		delete(h.nodes, nodeName)
 	}
+	h.strategy.SetNodes(h.getSortedNodes())
+}
 
-	hash := int(crc32.ChecksumIEEE([]byte(key)))
+func (h *HashRingT) InHashRing(nodeName string) (result bool) {
+	h.mu.RLock()
+	_, result = h.nodes[nodeName]
+	h.mu.RUnlock()
 
-	idx := sort.Search(len(h.nodes), func(i int) bool {
-		return h.nodes[i].hash >= hash
-	})
+	return result
+}
 
-	if idx == len(h.nodes) {
-		idx = 0
-	}
+// GetNodes returns a copy of the hashring nodes, sorted by name.
+func (h *HashRingT) GetNodes() (result []NodeT) {
+	h.mu.RLock()
+	result = h.getSortedNodes()
+	h.mu.RUnlock()
+
+	return result
+}
 
-	node = h.nodes[idx].name
+func (h *HashRingT) GetNode(key string) (node string) {
+	h.mu.RLock()
+	node = h.strategy.GetNode(key)
+	h.mu.RUnlock()
 
 	return node
 }
+
+func (h *HashRingT) getSortedNodes() (nodes []NodeT) {
+	nodes = make([]NodeT, 0, len(h.nodes))
+	for _, node := range h.nodes {
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, func(a, b NodeT) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return nodes
+}
+
+// getWeight returns the node weight, at least 1.
+func getWeight(node NodeT) int {
+	return max(node.Weight, 1)
+}
@@ -0,0 +1,214 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+)
+
+const (
+	testNodeCount = 10
+	testKeyCount  = 100000
+	testVNodes    = 128
+)
+
+// testMaxSkew is the max load of a node relative to the mean load by strategy
+var testMaxSkew = map[string]float64{
+	StrategyRing:       1.25,
+	StrategyRendezvous: 1.05,
+	StrategyJump:       1.05,
+	StrategyMaglev:     1.05,
+}
+
+func getTestNodes(count int) (nodes []NodeT) {
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, NodeT{Name: fmt.Sprintf("node-%02d", i), Weight: 1})
+	}
+	return nodes
+}
+
+func getTestKeys(count int) (keys []string) {
+	for i := 0; i < count; i++ {
+		keys = append(keys, fmt.Sprintf("bucket/path/to/object-%08d.bin", i))
+	}
+	return keys
+}
+
+func getTestHashRing(t testing.TB, strategy string, hashFunction string, nodes []NodeT) *HashRingT {
+	s, err := GetStrategy(strategy, hashFunction, testVNodes)
+	if err != nil {
+		t.Fatalf("unable to get strategy '%s' with hash function '%s': %s", strategy, hashFunction, err.Error())
+	}
+
+	h := NewHashRing(s)
+	h.AddNodes(nodes)
+	return h
+}
+
+func getAssignments(h *HashRingT, keys []string) (assignments map[string]string) {
+	assignments = map[string]string{}
+	for _, key := range keys {
+		assignments[key] = h.GetNode(key)
+	}
+	return assignments
+}
+
+func getMovedFraction(before map[string]string, after map[string]string) float64 {
+	moved := 0
+	for key, node := range before {
+		if after[key] != node {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(before))
+}
+
+func TestDistributionSkew(t *testing.T) {
+	keys := getTestKeys(testKeyCount)
+
+	for _, strategy := range Strategies {
+		for _, hashFunction := range HashFunctions {
+			t.Run(strategy+"/"+hashFunction, func(t *testing.T) {
+				h := getTestHashRing(t, strategy, hashFunction, getTestNodes(testNodeCount))
+
+				loads := map[string]int{}
+				for _, node := range getAssignments(h, keys) {
+					loads[node]++
+				}
+
+				if len(loads) != testNodeCount {
+					t.Fatalf("keys assigned to %d nodes, expected %d", len(loads), testNodeCount)
+				}
+
+				mean := float64(testKeyCount) / testNodeCount
+				for node, load := range loads {
+					if skew := float64(load) / mean; skew > testMaxSkew[strategy] {
+						t.Errorf("node '%s' load is %.3f times the mean load, expected at most %.3f",
+							node, skew, testMaxSkew[strategy])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestWeightedDistribution(t *testing.T) {
+	keys := getTestKeys(testKeyCount)
+
+	for _, strategy := range []string{StrategyRing, StrategyRendezvous, StrategyMaglev} {
+		t.Run(strategy, func(t *testing.T) {
+			nodes := getTestNodes(testNodeCount)
+			nodes[0].Weight = 2
+			h := getTestHashRing(t, strategy, HashFunctionXXHash, nodes)
+
+			loads := map[string]int{}
+			for _, node := range getAssignments(h, keys) {
+				loads[node]++
+			}
+
+			// the node with weight 2 gets 2 shares of the 11 ones
+			expected := 2 * float64(testKeyCount) / (testNodeCount + 1)
+			if ratio := float64(loads[nodes[0].Name]) / expected; ratio < 2-testMaxSkew[strategy] || ratio > testMaxSkew[strategy] {
+				t.Errorf("weighted node load is %.3f times the expected load", ratio)
+			}
+		})
+	}
+}
+
+func TestMovedKeysOnRemove(t *testing.T) {
+	keys := getTestKeys(testKeyCount)
+
+	for _, strategy := range Strategies {
+		for _, hashFunction := range HashFunctions {
+			t.Run(strategy+"/"+hashFunction, func(t *testing.T) {
+				nodes := getTestNodes(testNodeCount)
+				h := getTestHashRing(t, strategy, hashFunction, nodes)
+				before := getAssignments(h, keys)
+
+				// the jump strategy only keeps the keys of the rest of nodes when removing the last one
+				removed := nodes[testNodeCount/2].Name
+				if strategy == StrategyJump {
+					removed = nodes[testNodeCount-1].Name
+				}
+				h.RemoveNodes([]string{removed})
+				after := getAssignments(h, keys)
+
+				for key, node := range before {
+					if node != removed && strategy != StrategyMaglev && after[key] != node {
+						t.Fatalf("key '%s' moved from '%s' to '%s' with node '%s' removed", key, node, after[key], removed)
+					}
+				}
+
+				expected := 1.0 / testNodeCount
+				if moved := getMovedFraction(before, after); moved > expected*testMaxSkew[strategy]*1.1 {
+					t.Errorf("%.3f of the keys moved removing a node, expected about %.3f", moved, expected)
+				}
+			})
+		}
+	}
+}
+
+func TestMovedKeysOnAdd(t *testing.T) {
+	keys := getTestKeys(testKeyCount)
+
+	for _, strategy := range Strategies {
+		for _, hashFunction := range HashFunctions {
+			t.Run(strategy+"/"+hashFunction, func(t *testing.T) {
+				nodes := getTestNodes(testNodeCount + 1)
+				h := getTestHashRing(t, strategy, hashFunction, nodes[:testNodeCount])
+				before := getAssignments(h, keys)
+
+				added := nodes[testNodeCount]
+				h.AddNodes([]NodeT{added})
+				after := getAssignments(h, keys)
+
+				for key, node := range after {
+					if node != added.Name && strategy != StrategyMaglev && before[key] != node {
+						t.Fatalf("key '%s' moved from '%s' to '%s' with node '%s' added", key, before[key], node, added.Name)
+					}
+				}
+
+				expected := 1.0 / (testNodeCount + 1)
+				if moved := getMovedFraction(before, after); moved > expected*testMaxSkew[strategy]*1.1 {
+					t.Errorf("%.3f of the keys moved adding a node, expected about %.3f", moved, expected)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGetNode(b *testing.B) {
+	keys := getTestKeys(testKeyCount)
+
+	for _, strategy := range Strategies {
+		for _, hashFunction := range HashFunctions {
+			b.Run(strategy+"/"+hashFunction, func(b *testing.B) {
+				h := getTestHashRing(b, strategy, hashFunction, getTestNodes(testNodeCount))
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					h.GetNode(keys[i%len(keys)])
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkSetNodes(b *testing.B) {
+	nodes := getTestNodes(testNodeCount)
+
+	for _, strategy := range Strategies {
+		for _, hashFunction := range HashFunctions {
+			b.Run(strategy+"/"+hashFunction, func(b *testing.B) {
+				s, err := GetStrategy(strategy, hashFunction, testVNodes)
+				if err != nil {
+					b.Fatalf("unable to get strategy: %s", err.Error())
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					s.SetNodes(nodes)
+				}
+			})
+		}
+	}
+}
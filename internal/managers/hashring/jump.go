@@ -0,0 +1,44 @@
+package hashring
+
+// JumpStrategyT assigns every key to a bucket with the jump consistent hash, with the nodes
+// as buckets in name order. It needs no memory by key or vnode, but the node weights are ignored
+// and removing a node other than the last one in name order moves keys between the remaining nodes.
+type JumpStrategyT struct {
+	hash  HashFuncT
+	nodes []string
+}
+
+func NewJumpStrategy(hash HashFuncT) *JumpStrategyT {
+	return &JumpStrategyT{
+		hash: hash,
+	}
+}
+
+func (s *JumpStrategyT) SetNodes(nodes []NodeT) {
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	s.nodes = names
+}
+
+func (s *JumpStrategyT) GetNode(key string) string {
+	if len(s.nodes) == 0 {
+		return ""
+	}
+
+	return s.nodes[jumpHash(mix64(s.hash([]byte(key))), len(s.nodes))]
+}
+
+// jumpHash returns the bucket in [0, buckets) of the key, as defined in
+// "A Fast, Minimal Memory, Consistent Hash Algorithm" (Lamping and Veach).
+func jumpHash(key uint64, buckets int) int {
+	b, j := int64(-1), int64(0)
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,72 @@
+package hashring
+
+const (
+	// MaglevTableSize is the lookup table size, a prime much bigger than the number of nodes
+	MaglevTableSize = 65537
+)
+
+// MaglevStrategyT assigns every key to a lookup table entry, filled by the nodes following
+// their own permutation of the table with as many turns as their weight (Maglev hashing).
+// The lookups are constant time and the nodes changes only move a few keys more than the minimum.
+type MaglevStrategyT struct {
+	hash      HashFuncT
+	tableSize uint64
+	table     []int
+	nodes     []string
+}
+
+func NewMaglevStrategy(hash HashFuncT, tableSize int) *MaglevStrategyT {
+	return &MaglevStrategyT{
+		hash:      hash,
+		tableSize: uint64(tableSize),
+	}
+}
+
+func (s *MaglevStrategyT) SetNodes(nodes []NodeT) {
+	s.nodes = []string{}
+	s.table = nil
+	if len(nodes) == 0 {
+		return
+	}
+
+	offsets := make([]uint64, len(nodes))
+	skips := make([]uint64, len(nodes))
+	next := make([]uint64, len(nodes))
+	for i, node := range nodes {
+		s.nodes = append(s.nodes, node.Name)
+		offsets[i] = mix64(s.hash([]byte(node.Name))) % s.tableSize
+		skips[i] = mix64(s.hash([]byte(node.Name+"#skip")))%(s.tableSize-1) + 1
+	}
+
+	table := make([]int, s.tableSize)
+	for i := range table {
+		table[i] = -1
+	}
+
+	filled := uint64(0)
+	for filled < s.tableSize {
+		for i, node := range nodes {
+			for turn := 0; turn < getWeight(node) && filled < s.tableSize; turn++ {
+				entry := (offsets[i] + next[i]*skips[i]) % s.tableSize
+				for table[entry] >= 0 {
+					next[i]++
+					entry = (offsets[i] + next[i]*skips[i]) % s.tableSize
+				}
+
+				table[entry] = i
+				next[i]++
+				filled++
+			}
+		}
+	}
+
+	s.table = table
+}
+
+func (s *MaglevStrategyT) GetNode(key string) string {
+	if len(s.table) == 0 {
+		return ""
+	}
+
+	return s.nodes[s.table[mix64(s.hash([]byte(key)))%s.tableSize]]
+}
@@ -0,0 +1,66 @@
+package hashring
+
+import (
+	"math"
+)
+
+// RendezvousStrategyT assigns every key to the node with the highest score (HRW hashing),
+// computed from the key and node hashes and weighted with the logarithmic method,
+// so only the keys of a removed node are moved.
+type RendezvousStrategyT struct {
+	hash  HashFuncT
+	nodes []rendezvousNodeT
+}
+
+type rendezvousNodeT struct {
+	name   string
+	hash   uint64
+	weight float64
+}
+
+func NewRendezvousStrategy(hash HashFuncT) *RendezvousStrategyT {
+	return &RendezvousStrategyT{
+		hash: hash,
+	}
+}
+
+func (s *RendezvousStrategyT) SetNodes(nodes []NodeT) {
+	rnodes := []rendezvousNodeT{}
+	for _, node := range nodes {
+		rnodes = append(rnodes, rendezvousNodeT{
+			name:   node.Name,
+			hash:   s.hash([]byte(node.Name)),
+			weight: float64(getWeight(node)),
+		})
+	}
+
+	s.nodes = rnodes
+}
+
+func (s *RendezvousStrategyT) GetNode(key string) (node string) {
+	keyHash := s.hash([]byte(key))
+
+	bestScore := math.Inf(-1)
+	for _, rnode := range s.nodes {
+		// the mixed hash is mapped to (0, 1), the weighted score is -w/ln(u)
+		u := (float64(mix64(keyHash^rnode.hash)>>11) + 0.5) / (1 << 53)
+		score := -rnode.weight / math.Log(u)
+		if score > bestScore {
+			bestScore = score
+			node = rnode.name
+		}
+	}
+
+	return node
+}
+
+// mix64 is the splitmix64 finalizer, spreading the combined key and node hashes
+// even with hash functions of 32 bits.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
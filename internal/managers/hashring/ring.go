@@ -0,0 +1,74 @@
+package hashring
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// RingStrategyT places vnodes of every node in a ring of hashes, with the number of vnodes
+// scaled by the node weight, and assigns every key to the first vnode after its hash.
+// The hashes are mixed, as the vnode names only differ in the last characters.
+type RingStrategyT struct {
+	hash          HashFuncT
+	vnodesPerNode int
+	points        []ringPointT
+}
+
+type ringPointT struct {
+	hash uint64
+	name string
+}
+
+func NewRingStrategy(hash HashFuncT, vnodesPerNode int) *RingStrategyT {
+	return &RingStrategyT{
+		hash:          hash,
+		vnodesPerNode: max(vnodesPerNode, 1),
+	}
+}
+
+func (s *RingStrategyT) SetNodes(nodes []NodeT) {
+	points := []ringPointT{}
+	for _, node := range nodes {
+		for i := 0; i < s.vnodesPerNode*getWeight(node); i++ {
+			vnode := node.Name + "#" + strconv.Itoa(i)
+			points = append(points, ringPointT{hash: mix64(s.hash([]byte(vnode))), name: node.Name})
+		}
+	}
+
+	// the names break the hash collisions, so every instance builds the same ring
+	slices.SortFunc(points, func(a, b ringPointT) int {
+		if a.hash != b.hash {
+			if a.hash < b.hash {
+				return -1
+			}
+			return 1
+		}
+		if a.name < b.name {
+			return -1
+		}
+		if a.name > b.name {
+			return 1
+		}
+		return 0
+	})
+
+	s.points = points
+}
+
+func (s *RingStrategyT) GetNode(key string) string {
+	if len(s.points) == 0 {
+		return ""
+	}
+
+	hash := mix64(s.hash([]byte(key)))
+	idx := sort.Search(len(s.points), func(i int) bool {
+		return s.points[i].hash >= hash
+	})
+
+	if idx == len(s.points) {
+		idx = 0
+	}
+
+	return s.points[idx].name
+}
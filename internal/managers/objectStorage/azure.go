@@ -0,0 +1,244 @@
+package objectStorage
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+const (
+	AzureAuthSharedKey        = "sharedKey"
+	AzureAuthSAS              = "sas"
+	AzureAuthWorkloadIdentity = "workloadIdentity"
+)
+
+var (
+	AzureAuths = []string{AzureAuthSharedKey, AzureAuthSAS, AzureAuthWorkloadIdentity}
+)
+
+// AzureManagerT stores the objects in Azure Blob Storage, with the buckets as containers.
+type AzureManagerT struct {
+	ctx    context.Context
+	client *azblob.Client
+	upload v1alpha3.UploadConfigT
+}
+
+type AzureObjectT struct {
+	reader io.ReadCloser
+	info   ObjectInfoT
+}
+
+func (m *AzureManagerT) Init(ctx context.Context, config v1alpha3.SourceConfigT) (err error) {
+	m.ctx = ctx
+	m.upload = config.Upload
+
+	// the endpoint is set for the emulators, as Azurite, or the sovereign clouds
+	serviceURL := config.Azure.Endpoint
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", config.Azure.AccountName)
+	}
+
+	switch config.Azure.Auth {
+	case AzureAuthSharedKey:
+		{
+			var cred *azblob.SharedKeyCredential
+			cred, err = azblob.NewSharedKeyCredential(config.Azure.AccountName, config.Azure.AccountKey)
+			if err != nil {
+				return err
+			}
+			m.client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+		}
+	case AzureAuthSAS:
+		{
+			serviceURL = strings.TrimSuffix(serviceURL, "/") + "/?" + strings.TrimPrefix(config.Azure.SASToken, "?")
+			m.client, err = azblob.NewClientWithNoCredential(serviceURL, nil)
+		}
+	case AzureAuthWorkloadIdentity:
+		{
+			// the client, tenant and token file are taken from the environment
+			// injected by the workload identity webhook
+			var cred *azidentity.WorkloadIdentityCredential
+			cred, err = azidentity.NewWorkloadIdentityCredential(nil)
+			if err != nil {
+				return err
+			}
+			m.client, err = azblob.NewClient(serviceURL, cred, nil)
+		}
+	default:
+		{
+			err = fmt.Errorf("azure auth '%s' not supported", config.Azure.Auth)
+		}
+	}
+
+	return err
+}
+
+func (m *AzureManagerT) GetObject(obj ObjectT) (ro ObjectI, err error) {
+	info, err := m.StatObject(obj)
+	if err != nil {
+		return ro, err
+	}
+
+	res, err := m.client.DownloadStream(m.ctx, obj.Bucket, obj.Path, nil)
+	if err != nil {
+		return ro, getAzureError(err)
+	}
+
+	azobji := &AzureObjectT{}
+	azobji.reader = res.Body
+	azobji.info = info
+
+	ro = azobji
+	return ro, err
+}
+
+func (m *AzureManagerT) PutObject(obj ObjectT, ro ObjectI) (err error) {
+	contentType := ro.GetContentType()
+	headers := &blob.HTTPHeaders{
+		BlobContentType: &contentType,
+	}
+
+	// the Content-MD5 property is not computed by the service
+	// for the blobs uploaded in blocks
+	if md5 := ro.GetMD5String(); md5 != "" {
+		headers.BlobContentMD5, err = hex.DecodeString(md5)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the uncommitted blocks of the failed uploads are discarded by the service in a week
+	_, err = m.client.UploadStream(m.ctx, obj.Bucket, obj.Path, ro, &azblob.UploadStreamOptions{
+		BlockSize:   m.upload.PartSize,
+		Concurrency: m.upload.Concurrency,
+		HTTPHeaders: headers,
+	})
+
+	return err
+}
+
+func (m *AzureManagerT) StatObject(obj ObjectT) (info ObjectInfoT, err error) {
+	props, err := m.client.ServiceClient().NewContainerClient(obj.Bucket).NewBlobClient(obj.Path).GetProperties(m.ctx, nil)
+	if err != nil {
+		return info, getAzureError(err)
+	}
+
+	if props.ContentType != nil {
+		info.ContentType = *props.ContentType
+	}
+	if props.ContentLength != nil {
+		info.Size = *props.ContentLength
+	}
+	info.MD5 = hex.EncodeToString(props.ContentMD5)
+
+	return info, err
+}
+
+// List pages the blobs with the prefix, skipping the ones until startAfter
+// as the listings only continue from the opaque markers returned by the service.
+// The listing jobs use ListPage to continue from the markers instead.
+func (m *AzureManagerT) List(bucket string, prefix string, startAfter string, limit int) (objs []ObjectT, err error) {
+	pager := m.client.NewListBlobsFlatPager(bucket, &azblob.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+
+	for pager.More() && len(objs) < limit {
+		page, err := pager.NextPage(m.ctx)
+		if err != nil {
+			return objs, getAzureError(err)
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil || *item.Name <= startAfter {
+				continue
+			}
+
+			objs = append(objs, ObjectT{
+				Bucket: bucket,
+				Path:   *item.Name,
+			})
+			if len(objs) >= limit {
+				break
+			}
+		}
+	}
+
+	return objs, err
+}
+
+// ListPage returns a page of blobs with the prefix from the marker returned by the service
+// in the previous page. The pages can have less than limit blobs before the last one.
+func (m *AzureManagerT) ListPage(bucket string, prefix string, marker string, limit int) (objs []ObjectT, nextMarker string, err error) {
+	maxResults := int32(limit)
+	options := &azblob.ListBlobsFlatOptions{
+		Prefix:     &prefix,
+		MaxResults: &maxResults,
+	}
+	if marker != "" {
+		options.Marker = &marker
+	}
+
+	page, err := m.client.NewListBlobsFlatPager(bucket, options).NextPage(m.ctx)
+	if err != nil {
+		return objs, nextMarker, getAzureError(err)
+	}
+
+	for _, item := range page.Segment.BlobItems {
+		if item.Name == nil {
+			continue
+		}
+
+		objs = append(objs, ObjectT{
+			Bucket: bucket,
+			Path:   *item.Name,
+		})
+	}
+
+	if page.NextMarker != nil {
+		nextMarker = *page.NextMarker
+	}
+
+	return objs, nextMarker, err
+}
+
+// getAzureError returns ErrObjectNotFound for the missing blobs and containers.
+func getAzureError(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return ErrObjectNotFound
+	}
+	return err
+}
+
+func (o *AzureObjectT) GetContentType() string {
+	return o.info.ContentType
+}
+
+func (o *AzureObjectT) GetSize() int64 {
+	return o.info.Size
+}
+
+func (o *AzureObjectT) GetMD5String() string {
+	return o.info.MD5
+}
+
+func (o *AzureObjectT) GetChecksum() (algorithm string, checksum string) {
+	return o.info.GetSourceChecksum()
+}
+
+func (o *AzureObjectT) Read(p []byte) (n int, err error) {
+	n, err = o.reader.Read(p)
+	return n, err
+}
+
+func (o *AzureObjectT) Close() error {
+	err := o.reader.Close()
+	return err
+}
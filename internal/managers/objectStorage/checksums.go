@@ -0,0 +1,59 @@
+package objectStorage
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"regexp"
+)
+
+const (
+	ChecksumAlgorithmMD5    = "md5"
+	ChecksumAlgorithmCRC32C = "crc32c"
+	ChecksumAlgorithmSHA256 = "sha256"
+)
+
+var (
+	md5Regexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// NewChecksumHash returns a new hash for the checksum algorithm.
+func NewChecksumHash(algorithm string) (h hash.Hash, err error) {
+	switch algorithm {
+	case ChecksumAlgorithmMD5:
+		h = md5.New()
+	case ChecksumAlgorithmCRC32C:
+		h = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case ChecksumAlgorithmSHA256:
+		h = sha256.New()
+	default:
+		err = fmt.Errorf("checksum algorithm '%s' not supported", algorithm)
+	}
+
+	return h, err
+}
+
+// IsMD5 returns true when the value is a hex encoded MD5, which is not the case
+// for the ETag of the objects uploaded in multiple parts.
+func IsMD5(value string) bool {
+	return md5Regexp.MatchString(value)
+}
+
+// base64ToHex converts the base64 checksums returned in the S3 headers
+// to the hex encoding used in the rest of the checksums.
+func base64ToHex(value string) string {
+	if value == "" {
+		return value
+	}
+
+	valueBytes, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(valueBytes)
+}
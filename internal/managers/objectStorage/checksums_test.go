@@ -0,0 +1,128 @@
+package objectStorage
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestNewChecksumHash(t *testing.T) {
+	tests := []struct {
+		algorithm string
+
+		expected    string
+		expectedErr bool
+	}{
+		{algorithm: ChecksumAlgorithmMD5, expected: "25f9e794323b453885f5181f1b624d0b"},
+		{algorithm: ChecksumAlgorithmCRC32C, expected: "e3069283"},
+		{algorithm: ChecksumAlgorithmSHA256, expected: "15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225"},
+		{algorithm: "sha1", expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			h, err := NewChecksumHash(test.algorithm)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected unsupported algorithm error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to get hash: %s", err.Error())
+			}
+
+			h.Write([]byte("123456789"))
+			if checksum := hex.EncodeToString(h.Sum(nil)); checksum != test.expected {
+				t.Fatalf("checksum is '%s', expected '%s'", checksum, test.expected)
+			}
+		})
+	}
+}
+
+func TestIsMD5(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+
+		expected bool
+	}{
+		{name: "hex md5", value: "25f9e794323b453885f5181f1b624d0b", expected: true},
+		{name: "uppercase md5", value: "25F9E794323B453885F5181F1B624D0B", expected: false},
+		{name: "multipart etag", value: "25f9e794323b453885f5181f1b624d0b-3", expected: false},
+		{name: "quoted etag", value: `"25f9e794323b453885f5181f1b624d0b"`, expected: false},
+		{name: "sha256", value: "15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225", expected: false},
+		{name: "empty", value: "", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if isMD5 := IsMD5(test.value); isMD5 != test.expected {
+				t.Fatalf("is md5 is %t, expected %t", isMD5, test.expected)
+			}
+		})
+	}
+}
+
+func TestBase64ToHex(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+
+		expected string
+	}{
+		{name: "base64 md5", value: "JfnnlDI7RTiF9RgfG2JNCw==", expected: "25f9e794323b453885f5181f1b624d0b"},
+		{name: "empty", value: "", expected: ""},
+		{name: "not base64", value: "not base64!", expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := base64ToHex(test.value); value != test.expected {
+				t.Fatalf("hex value is '%s', expected '%s'", value, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetSourceChecksum(t *testing.T) {
+	tests := []struct {
+		name string
+		info ObjectInfoT
+
+		expectedAlgorithm string
+		expectedChecksum  string
+	}{
+		{
+			name:              "md5 preferred",
+			info:              ObjectInfoT{MD5: "md5", CRC32C: "crc32c", SHA256: "sha256"},
+			expectedAlgorithm: ChecksumAlgorithmMD5,
+			expectedChecksum:  "md5",
+		},
+		{
+			name:              "sha256 before crc32c",
+			info:              ObjectInfoT{CRC32C: "crc32c", SHA256: "sha256"},
+			expectedAlgorithm: ChecksumAlgorithmSHA256,
+			expectedChecksum:  "sha256",
+		},
+		{
+			name:              "crc32c",
+			info:              ObjectInfoT{CRC32C: "crc32c"},
+			expectedAlgorithm: ChecksumAlgorithmCRC32C,
+			expectedChecksum:  "crc32c",
+		},
+		{
+			name: "no checksum",
+			info: ObjectInfoT{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			algorithm, checksum := test.info.GetSourceChecksum()
+			if algorithm != test.expectedAlgorithm || checksum != test.expectedChecksum {
+				t.Fatalf("source checksum is %s '%s', expected %s '%s'",
+					algorithm, checksum, test.expectedAlgorithm, test.expectedChecksum)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package objectStorage
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// filesystemFileMode is the mode of the object files, as the temporary files
+	// are created readable only by the owner
+	filesystemFileMode = 0o644
+)
+
+// FilesystemManagerT stores the objects as files in a local or NFS mounted directory,
+// with a subdirectory by bucket and the object paths as file paths inside it.
+type FilesystemManagerT struct {
+	ctx       context.Context
+	directory string
+}
+
+type FilesystemObjectT struct {
+	file *os.File
+	info ObjectInfoT
+}
+
+func (m *FilesystemManagerT) Init(ctx context.Context, config v1alpha3.SourceConfigT) (err error) {
+	m.ctx = ctx
+	m.directory = config.Filesystem.Directory
+
+	stat, err := os.Stat(m.directory)
+	if err != nil {
+		return err
+	}
+
+	if !stat.IsDir() {
+		err = fmt.Errorf("filesystem source path '%s' is not a directory", m.directory)
+	}
+
+	return err
+}
+
+func (m *FilesystemManagerT) GetObject(obj ObjectT) (ro ObjectI, err error) {
+	filename, err := m.getFilename(obj)
+	if err != nil {
+		return ro, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = ErrObjectNotFound
+		}
+		return ro, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		file.Close()
+		if err == nil {
+			err = ErrObjectNotFound
+		}
+		return ro, err
+	}
+
+	// the md5 is not read here, the object worker computes it while copying
+	fsobji := &FilesystemObjectT{}
+	fsobji.file = file
+	fsobji.info = ObjectInfoT{
+		ContentType: getContentType(obj.Path),
+		Size:        stat.Size(),
+	}
+
+	ro = fsobji
+	return ro, err
+}
+
+// PutObject writes the object in a temporary file in the same directory, synced and renamed
+// to the object file when it is complete, so the readers never see partial objects.
+func (m *FilesystemManagerT) PutObject(obj ObjectT, ro ObjectI) (err error) {
+	filename, err := m.getFilename(obj)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, ro)
+	if err == nil {
+		err = tmp.Chmod(filesystemFileMode)
+	}
+	if err == nil {
+		// the content is flushed to disk before the rename makes it visible
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// StatObject returns the file attributes, reading the file to compute its md5
+// as the filesystems do not store it.
+func (m *FilesystemManagerT) StatObject(obj ObjectT) (info ObjectInfoT, err error) {
+	filename, err := m.getFilename(obj)
+	if err != nil {
+		return info, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = ErrObjectNotFound
+		}
+		return info, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return info, err
+	}
+
+	if stat.IsDir() {
+		return info, ErrObjectNotFound
+	}
+
+	h := md5.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return info, err
+	}
+
+	info.ContentType = getContentType(obj.Path)
+	info.Size = stat.Size()
+	info.MD5 = hex.EncodeToString(h.Sum(nil))
+
+	return info, err
+}
+
+// List walks the bucket directory, sorting the file paths as the directories
+// are walked in name order and not in path order ('a/b' is walked before 'a-c').
+func (m *FilesystemManagerT) List(bucket string, prefix string, startAfter string, limit int) (objs []ObjectT, err error) {
+	bucketDir, err := m.getBucketDirectory(bucket)
+	if err != nil {
+		return objs, err
+	}
+
+	// the bucket directory is only created with its first object
+	if _, err = os.Stat(bucketDir); errors.Is(err, fs.ErrNotExist) {
+		return objs, nil
+	}
+
+	paths := []string{}
+	err = filepath.WalkDir(bucketDir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(bucketDir, filename)
+		if err != nil {
+			return err
+		}
+		objPath := filepath.ToSlash(relPath)
+
+		if entry.IsDir() {
+			if objPath == "." {
+				return nil
+			}
+
+			// skip the directories out of the prefix
+			dirPath := objPath + "/"
+			if !strings.HasPrefix(dirPath, prefix) && !strings.HasPrefix(prefix, dirPath) {
+				return filepath.SkipDir
+			}
+
+			// skip the directories with all their paths sorted before the start
+			if dirPath <= startAfter && !strings.HasPrefix(startAfter, dirPath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// temporary files of the objects being written
+		if strings.HasPrefix(entry.Name(), ".") && strings.HasSuffix(entry.Name(), ".tmp") {
+			return nil
+		}
+
+		if strings.HasPrefix(objPath, prefix) && objPath > startAfter {
+			paths = append(paths, objPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return objs, err
+	}
+
+	slices.Sort(paths)
+	for _, objPath := range paths[:min(limit, len(paths))] {
+		objs = append(objs, ObjectT{
+			Bucket: bucket,
+			Path:   objPath,
+		})
+	}
+
+	return objs, err
+}
+
+// getBucketDirectory returns the bucket directory, rejecting the bucket names
+// that are not a single directory name.
+func (m *FilesystemManagerT) getBucketDirectory(bucket string) (dir string, err error) {
+	if bucket == "" || bucket == "." || bucket == ".." || strings.ContainsAny(bucket, `/\`) {
+		err = fmt.Errorf("invalid filesystem bucket name '%s'", bucket)
+		return dir, err
+	}
+
+	return filepath.Join(m.directory, bucket), err
+}
+
+// getFilename returns the object file, rejecting the object paths out of the bucket directory.
+func (m *FilesystemManagerT) getFilename(obj ObjectT) (filename string, err error) {
+	bucketDir, err := m.getBucketDirectory(obj.Bucket)
+	if err != nil {
+		return filename, err
+	}
+
+	if strings.Trim(obj.Path, "/") == "" || slices.Contains(strings.Split(obj.Path, "/"), "..") {
+		err = fmt.Errorf("invalid filesystem object path '%s'", obj.Path)
+		return filename, err
+	}
+
+	return filepath.Join(bucketDir, filepath.FromSlash(obj.Path)), err
+}
+
+// getContentType returns the content type known for the object path extension.
+func getContentType(objPath string) string {
+	contentType := mime.TypeByExtension(path.Ext(objPath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return contentType
+}
+
+func (o *FilesystemObjectT) GetContentType() string {
+	return o.info.ContentType
+}
+
+func (o *FilesystemObjectT) GetSize() int64 {
+	return o.info.Size
+}
+
+func (o *FilesystemObjectT) GetMD5String() string {
+	return o.info.MD5
+}
+
+func (o *FilesystemObjectT) GetChecksum() (algorithm string, checksum string) {
+	return o.info.GetSourceChecksum()
+}
+
+func (o *FilesystemObjectT) Read(p []byte) (n int, err error) {
+	n, err = o.file.Read(p)
+	return n, err
+}
+
+func (o *FilesystemObjectT) Close() error {
+	err := o.file.Close()
+	return err
+}
@@ -0,0 +1,79 @@
+package objectStorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bot/api/v1alpha3"
+)
+
+func TestFilesystemPutObject(t *testing.T) {
+	directory := t.TempDir()
+	m := &FilesystemManagerT{}
+	err := m.Init(context.Background(), v1alpha3.SourceConfigT{
+		Filesystem: v1alpha3.FilesystemT{Directory: directory},
+	})
+	if err != nil {
+		t.Fatalf("unable to init manager: %s", err.Error())
+	}
+
+	obj := ObjectT{Bucket: "bucket", Path: "path/object.txt"}
+	for _, content := range []string{"first", "second"} {
+		if err = m.PutObject(obj, newTestObject([]byte(content))); err != nil {
+			t.Fatalf("unable to put object: %s", err.Error())
+		}
+	}
+
+	filename := filepath.Join(directory, "bucket", "path", "object.txt")
+	stat, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("unable to stat object file: %s", err.Error())
+	}
+	if mode := stat.Mode().Perm(); mode != filesystemFileMode {
+		t.Fatalf("object file mode is %o, expected %o", mode, filesystemFileMode)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil || string(content) != "second" {
+		t.Fatalf("object file content is '%s', expected 'second' (%v)", content, err)
+	}
+
+	// the temporary files are removed once renamed
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("object directory has %d entries, expected only the object file (%v)", len(entries), err)
+	}
+
+	info, err := m.StatObject(obj)
+	if err != nil {
+		t.Fatalf("unable to stat object: %s", err.Error())
+	}
+	if info.ContentType != "text/plain; charset=utf-8" || info.Size != int64(len("second")) ||
+		info.MD5 != "a9f0e61a137d86aa9db53465e0801612" {
+		t.Fatalf("object info is %+v", info)
+	}
+}
+
+func TestFilesystemInvalidObjects(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  ObjectT
+	}{
+		{name: "empty bucket", obj: ObjectT{Bucket: "", Path: "object"}},
+		{name: "bucket with separator", obj: ObjectT{Bucket: "bucket/other", Path: "object"}},
+		{name: "parent bucket", obj: ObjectT{Bucket: "..", Path: "object"}},
+		{name: "empty path", obj: ObjectT{Bucket: "bucket", Path: "/"}},
+		{name: "path out of bucket", obj: ObjectT{Bucket: "bucket", Path: "path/../../object"}},
+	}
+
+	m := &FilesystemManagerT{directory: t.TempDir()}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := m.getFilename(test.obj); err == nil {
+				t.Fatalf("expected invalid object error")
+			}
+		})
+	}
+}
@@ -3,83 +3,178 @@ package objectStorage
 import (
 	"bot/api/v1alpha3"
 	"context"
+	"encoding/binary"
 	"encoding/hex"
-	"fmt"
+	"errors"
 	"io"
+	"net/http"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
 type GCSManagerT struct {
 	ctx    context.Context
 	client *storage.Client
+	upload v1alpha3.UploadConfigT
+
+	// credentialsFile identifies the credentials, as the rewrites
+	// read the source with the destination credentials
+	credentialsFile string
 }
 
 type GCSObjectT struct {
-	reader      io.ReadCloser
-	md5Sum      string
-	size        int64
-	contentType string
+	reader io.ReadCloser
+	info   ObjectInfoT
 }
 
 func (m *GCSManagerT) Init(ctx context.Context, config v1alpha3.SourceConfigT) (err error) {
 	m.ctx = ctx
 	m.client, err = storage.NewClient(m.ctx, option.WithCredentialsFile(config.GCS.CredentialsFile))
+	m.upload = config.Upload
+	m.credentialsFile = config.GCS.CredentialsFile
 
 	return err
 }
 
 func (m *GCSManagerT) GetObject(obj ObjectT) (ro ObjectI, err error) {
-	objgcs := m.client.Bucket(obj.Bucket).Object(obj.Path)
-	stat, err := objgcs.Attrs(m.ctx)
+	info, err := m.StatObject(obj)
 	if err != nil {
 		return ro, err
 	}
 
-	if len(stat.MD5) == 0 {
-		err = fmt.Errorf("object '%s' without md5 assosiated in '%s' source bucket", obj.Path, obj.Bucket)
-		return ro, err
-	}
-
 	objgcsi := &GCSObjectT{}
-	objgcsi.reader, err = objgcs.NewReader(m.ctx)
+	objgcsi.reader, err = m.client.Bucket(obj.Bucket).Object(obj.Path).NewReader(m.ctx)
 	if err != nil {
 		return ro, err
 	}
-	objgcsi.md5Sum = hex.EncodeToString(stat.MD5)
-	objgcsi.size = stat.Size
-	objgcsi.contentType = stat.ContentType
+	objgcsi.info = info
 
 	ro = objgcsi
 	return ro, err
 }
 
+// PutObject uploads the object in a resumable upload session, sending a chunk of the part size
+// in every request. When the uploads are resumable, the chunks are retried on any error, resuming
+// the session from the last chunk persisted. The sessions are aborted on error, as they can not be
+// resumed by other writer, and the ones abandoned expire in a week.
 func (m *GCSManagerT) PutObject(obj ObjectT, ro ObjectI) (err error) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
 	gcsobj := m.client.Bucket(obj.Bucket).Object(obj.Path)
-	wo := gcsobj.NewWriter(m.ctx)
-	defer wo.Close()
+	if m.upload.Resumable {
+		gcsobj = gcsobj.Retryer(storage.WithPolicy(storage.RetryAlways))
+	}
+
+	wo := gcsobj.NewWriter(ctx)
+	wo.ChunkSize = int(m.upload.PartSize)
 	wo.ContentType = ro.GetContentType()
-	wo.Size = ro.GetSize()
 	wo.MD5, err = hex.DecodeString(ro.GetMD5String())
 	if err != nil {
 		return err
 	}
 
 	_, err = io.Copy(wo, ro)
+	if err != nil {
+		// the canceled context aborts the upload instead of finishing it with the content copied
+		cancel()
+		wo.Close()
+		return err
+	}
+
+	// the object is only created when the writer is closed
+	return wo.Close()
+}
+
+func (m *GCSManagerT) StatObject(obj ObjectT) (info ObjectInfoT, err error) {
+	stat, err := m.client.Bucket(obj.Bucket).Object(obj.Path).Attrs(m.ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			err = ErrObjectNotFound
+		}
+		return info, err
+	}
+
+	info.ContentType = stat.ContentType
+	info.Size = stat.Size
+	// composite objects do not have md5
+	info.MD5 = hex.EncodeToString(stat.MD5)
+	info.CRC32C = hex.EncodeToString(binary.BigEndian.AppendUint32(nil, stat.CRC32C))
+
+	return info, err
+}
+
+func (m *GCSManagerT) List(bucket string, prefix string, startAfter string, limit int) (objs []ObjectT, err error) {
+	query := &storage.Query{
+		Prefix:      prefix,
+		StartOffset: startAfter,
+	}
+	if err = query.SetAttrSelection([]string{"Name"}); err != nil {
+		return objs, err
+	}
+
+	it := m.client.Bucket(bucket).Objects(m.ctx, query)
+	for len(objs) < limit {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return objs, err
+		}
+
+		// start offset is inclusive
+		if attrs.Name == startAfter {
+			continue
+		}
+
+		objs = append(objs, ObjectT{
+			Bucket: bucket,
+			Path:   attrs.Name,
+		})
+	}
+
+	return objs, err
+}
+
+func (m *GCSManagerT) CanCopyFrom(source ObjectManagerI) bool {
+	gcsSource, ok := source.(*GCSManagerT)
+	return ok && gcsSource.credentialsFile == m.credentialsFile
+}
+
+// CopyObject rewrites the object, in as many calls as needed for the big objects
+// copied between locations or storage classes, keeping its metadata.
+func (m *GCSManagerT) CopyObject(src ObjectT, dst ObjectT) (err error) {
+	srcobj := m.client.Bucket(src.Bucket).Object(src.Path)
+	dstobj := m.client.Bucket(dst.Bucket).Object(dst.Path)
+
+	// the rewrites return the not found api error instead of storage.ErrObjectNotExist
+	_, err = dstobj.CopierFrom(srcobj).Run(m.ctx)
+	apiErr := &googleapi.Error{}
+	if errors.Is(err, storage.ErrObjectNotExist) || errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		err = ErrObjectNotFound
+	}
+
 	return err
 }
 
 func (o *GCSObjectT) GetContentType() string {
-	return o.contentType
+	return o.info.ContentType
 }
 
 func (o *GCSObjectT) GetSize() int64 {
-	return o.size
+	return o.info.Size
 }
 
 func (o *GCSObjectT) GetMD5String() string {
-	return o.md5Sum
+	return o.info.MD5
+}
+
+func (o *GCSObjectT) GetChecksum() (algorithm string, checksum string) {
+	return o.info.GetSourceChecksum()
 }
 
 func (o *GCSObjectT) Read(p []byte) (n int, err error) {
@@ -0,0 +1,225 @@
+package objectStorage
+
+import (
+	"bot/api/v1alpha3"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// HTTPTemplateBucket and HTTPTemplatePath are the URL template placeholders
+	// replaced with the bucket and the escaped object path
+	HTTPTemplateBucket = "{bucket}"
+	HTTPTemplatePath   = "{path}"
+)
+
+// HTTPManagerT reads the objects from an HTTP origin, with the object URL built
+// from the bucket and the path with the URL template. It is a read-only source.
+type HTTPManagerT struct {
+	ctx         context.Context
+	client      *http.Client
+	urlTemplate string
+	headers     map[string]string
+	username    string
+	password    string
+	bearerToken string
+	md5Header   string
+	timeout     time.Duration
+}
+
+// HTTPObjectT reads the response body, canceling the request when no content
+// is read for the timeout.
+type HTTPObjectT struct {
+	reader  io.ReadCloser
+	info    ObjectInfoT
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (m *HTTPManagerT) Init(ctx context.Context, config v1alpha3.SourceConfigT) (err error) {
+	m.ctx = ctx
+	m.urlTemplate = config.HTTP.URLTemplate
+	m.headers = config.HTTP.Headers
+	m.username = config.HTTP.Username
+	m.password = config.HTTP.Password
+	m.bearerToken = config.HTTP.BearerToken
+	m.md5Header = config.HTTP.MD5Header
+
+	m.timeout = config.HTTP.Timeout
+	if m.timeout <= 0 {
+		m.timeout = 30 * time.Second
+	}
+
+	// the timeout is the one of the response headers and of every body read, not the one
+	// of the whole body, as the objects can be big
+	m.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: m.timeout,
+			TLSHandshakeTimeout:   m.timeout,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+
+	return err
+}
+
+func (m *HTTPManagerT) GetObject(obj ObjectT) (ro ObjectI, err error) {
+	ctx, cancel := context.WithCancelCause(m.ctx)
+	res, err := m.doRequest(ctx, http.MethodGet, obj)
+	if err != nil {
+		cancel(nil)
+		return ro, err
+	}
+
+	httpobji := &HTTPObjectT{}
+	httpobji.reader = res.Body
+	httpobji.info = m.getObjectInfo(res)
+	httpobji.ctx = ctx
+	httpobji.cancel = cancel
+	httpobji.timeout = m.timeout
+	httpobji.timer = time.AfterFunc(m.timeout, func() {
+		cancel(fmt.Errorf("http origin body read stalled for %s", m.timeout))
+	})
+
+	ro = httpobji
+	return ro, err
+}
+
+func (m *HTTPManagerT) PutObject(obj ObjectT, ro ObjectI) (err error) {
+	err = fmt.Errorf("unable to put object '%s' in http source: %w", obj.String(), ErrReadOnlySource)
+	return err
+}
+
+func (m *HTTPManagerT) StatObject(obj ObjectT) (info ObjectInfoT, err error) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	res, err := m.doRequest(ctx, http.MethodHead, obj)
+	if err != nil {
+		return info, err
+	}
+	res.Body.Close()
+
+	return m.getObjectInfo(res), err
+}
+
+func (m *HTTPManagerT) List(bucket string, prefix string, startAfter string, limit int) (objs []ObjectT, err error) {
+	err = fmt.Errorf("http sources do not support listing objects")
+	return objs, err
+}
+
+// doRequest requests the object URL with the configured headers and auth, returning
+// ErrObjectNotFound on 404 responses and an error on the rest of non 2xx responses.
+func (m *HTTPManagerT) doRequest(ctx context.Context, method string, obj ObjectT) (res *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, m.getURL(obj), nil)
+	if err != nil {
+		return res, err
+	}
+
+	for key, value := range m.headers {
+		req.Header.Set(key, value)
+	}
+
+	if m.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+m.bearerToken)
+	} else if m.username != "" {
+		req.SetBasicAuth(m.username, m.password)
+	}
+
+	res, err = m.client.Do(req)
+	if err != nil {
+		return res, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			return res, ErrObjectNotFound
+		}
+		err = fmt.Errorf("http origin responded with '%s' status to %s '%s'", res.Status, method, req.URL.Redacted())
+	}
+
+	return res, err
+}
+
+// getURL returns the object URL from the template, escaping every path segment.
+func (m *HTTPManagerT) getURL(obj ObjectT) string {
+	segments := strings.Split(obj.Path, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+
+	return strings.NewReplacer(
+		HTTPTemplateBucket, url.PathEscape(obj.Bucket),
+		HTTPTemplatePath, strings.Join(segments, "/"),
+	).Replace(m.urlTemplate)
+}
+
+// getObjectInfo returns the object attributes in the response headers, with the md5 taken
+// from the Content-MD5 header or the configured md5 header. The ETag is not used, as it is
+// an opaque validator that can be a hex hash other than the md5 of the content.
+func (m *HTTPManagerT) getObjectInfo(res *http.Response) (info ObjectInfoT) {
+	info.ContentType = res.Header.Get("Content-Type")
+	info.Size = res.ContentLength
+
+	if md5 := base64ToHex(res.Header.Get("Content-MD5")); IsMD5(md5) {
+		info.MD5 = md5
+		return info
+	}
+
+	if m.md5Header == "" {
+		return info
+	}
+
+	value := res.Header.Get(m.md5Header)
+	if md5 := strings.ToLower(value); IsMD5(md5) {
+		info.MD5 = md5
+	} else if md5 := base64ToHex(value); IsMD5(md5) {
+		info.MD5 = md5
+	}
+
+	return info
+}
+
+func (o *HTTPObjectT) GetContentType() string {
+	return o.info.ContentType
+}
+
+func (o *HTTPObjectT) GetSize() int64 {
+	return o.info.Size
+}
+
+func (o *HTTPObjectT) GetMD5String() string {
+	return o.info.MD5
+}
+
+func (o *HTTPObjectT) GetChecksum() (algorithm string, checksum string) {
+	return o.info.GetSourceChecksum()
+}
+
+// Read reads the response body, restarting the read timeout, and returns the timeout
+// error when the request is canceled by it.
+func (o *HTTPObjectT) Read(p []byte) (n int, err error) {
+	n, err = o.reader.Read(p)
+	if err != nil && err != io.EOF && o.ctx.Err() != nil {
+		err = context.Cause(o.ctx)
+	}
+	o.timer.Reset(o.timeout)
+
+	return n, err
+}
+
+func (o *HTTPObjectT) Close() error {
+	o.timer.Stop()
+	err := o.reader.Close()
+	o.cancel(nil)
+	return err
+}
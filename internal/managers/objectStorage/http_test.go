@@ -0,0 +1,185 @@
+package objectStorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bot/api/v1alpha3"
+)
+
+func getTestHTTPManager(t *testing.T, url string, md5Header string) *HTTPManagerT {
+	t.Helper()
+
+	m := &HTTPManagerT{}
+	err := m.Init(context.Background(), v1alpha3.SourceConfigT{
+		HTTP: v1alpha3.HTTPT{
+			URLTemplate: url + "/" + HTTPTemplateBucket + "/" + HTTPTemplatePath,
+			Timeout:     200 * time.Millisecond,
+			MD5Header:   md5Header,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to init manager: %s", err.Error())
+	}
+
+	return m
+}
+
+func TestHTTPGetObject(t *testing.T) {
+	// release unblocks the stalled responses when the test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bucket/complete":
+			{
+				w.Write([]byte("content"))
+			}
+		case "/bucket/stalled":
+			{
+				w.Header().Set("Content-Length", "14")
+				w.Write([]byte("content"))
+				w.(http.Flusher).Flush()
+				<-release
+			}
+		default:
+			{
+				http.NotFound(w, r)
+			}
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	tests := []struct {
+		name string
+		path string
+
+		expectedContent string
+		expectedGetErr  bool
+		expectedReadErr bool
+	}{
+		{
+			name:            "complete body",
+			path:            "complete",
+			expectedContent: "content",
+		},
+		{
+			name:            "stalled body",
+			path:            "stalled",
+			expectedReadErr: true,
+		},
+		{
+			name:           "missing object",
+			path:           "missing",
+			expectedGetErr: true,
+		},
+	}
+
+	m := getTestHTTPManager(t, server.URL, "")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ro, err := m.GetObject(ObjectT{Bucket: "bucket", Path: test.path})
+			if test.expectedGetErr {
+				if err != ErrObjectNotFound {
+					t.Fatalf("get error is '%v', expected '%v'", err, ErrObjectNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to get object: %s", err.Error())
+			}
+			defer ro.Close()
+
+			done := make(chan struct{})
+			var content []byte
+			go func() {
+				content, err = io.ReadAll(ro)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("body read not canceled by the timeout")
+			}
+
+			if test.expectedReadErr {
+				if err == nil || !strings.Contains(err.Error(), "stalled") {
+					t.Fatalf("read error is '%v', expected a stalled body error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to read object: %s", err.Error())
+			}
+			if string(content) != test.expectedContent {
+				t.Fatalf("object content is '%s', expected '%s'", content, test.expectedContent)
+			}
+		})
+	}
+}
+
+func TestHTTPStatObjectMD5(t *testing.T) {
+	tests := []struct {
+		name      string
+		md5Header string
+		headers   map[string]string
+
+		expectedMD5 string
+	}{
+		{
+			name:        "content md5",
+			headers:     map[string]string{"Content-MD5": "mZFLkyvTelC5g8XnyQrpOw=="},
+			expectedMD5: "99914b932bd37a50b983c5e7c90ae93b",
+		},
+		{
+			name:        "hex etag is not the md5",
+			headers:     map[string]string{"ETag": `"99914b932bd37a50b983c5e7c90ae93b"`},
+			expectedMD5: "",
+		},
+		{
+			name:        "configured hex md5 header",
+			md5Header:   "X-Checksum-Md5",
+			headers:     map[string]string{"X-Checksum-Md5": "99914B932BD37A50B983C5E7C90AE93B"},
+			expectedMD5: "99914b932bd37a50b983c5e7c90ae93b",
+		},
+		{
+			name:        "configured base64 md5 header",
+			md5Header:   "X-Checksum-Md5",
+			headers:     map[string]string{"X-Checksum-Md5": "mZFLkyvTelC5g8XnyQrpOw=="},
+			expectedMD5: "99914b932bd37a50b983c5e7c90ae93b",
+		},
+		{
+			name:        "invalid configured md5 header",
+			md5Header:   "X-Checksum-Md5",
+			headers:     map[string]string{"X-Checksum-Md5": "not-a-md5"},
+			expectedMD5: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, value := range test.headers {
+					w.Header().Set(key, value)
+				}
+				w.Write([]byte("{}"))
+			}))
+			defer server.Close()
+
+			m := getTestHTTPManager(t, server.URL, test.md5Header)
+			info, err := m.StatObject(ObjectT{Bucket: "bucket", Path: "object"})
+			if err != nil {
+				t.Fatalf("unable to stat object: %s", err.Error())
+			}
+			if info.MD5 != test.expectedMD5 {
+				t.Fatalf("object md5 is '%s', expected '%s'", info.MD5, test.expectedMD5)
+			}
+		})
+	}
+}
@@ -3,22 +3,114 @@ package objectStorage
 import (
 	"bot/api/v1alpha3"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
+const (
+	TypeS3         = "s3"
+	TypeGCS        = "gcs"
+	TypeAzure      = "azure"
+	TypeFilesystem = "filesystem"
+	TypeMemory     = "memory"
+	TypeHTTP       = "http"
+)
+
+var (
+	Types = []string{TypeS3, TypeGCS, TypeAzure, TypeFilesystem, TypeMemory, TypeHTTP}
+	// ReadOnlyTypes are the source types that can not be used as frontend
+	ReadOnlyTypes = []string{TypeHTTP}
+
+	ErrObjectNotFound = errors.New("object not found")
+	ErrReadOnlySource = errors.New("source is read-only")
+)
+
 type ObjectManagerI interface {
 	Init(ctx context.Context, config v1alpha3.SourceConfigT) error
 	GetObject(obj ObjectT) (obji ObjectI, err error)
 	PutObject(obj ObjectT, ro ObjectI) (err error)
+	// StatObject returns the object attributes without reading its content,
+	// or ErrObjectNotFound when the object does not exist.
+	StatObject(obj ObjectT) (info ObjectInfoT, err error)
+	// List returns up to limit objects in the bucket with the prefix, in lexicographical order
+	// and starting after the startAfter object path (empty to start from the beginning).
+	List(bucket string, prefix string, startAfter string, limit int) (objs []ObjectT, err error)
+}
+
+// UploadCleanerI is implemented by the managers leaving the parts of the unfinished uploads
+// in the backend until they are aborted.
+type UploadCleanerI interface {
+	// CleanUploads aborts the unfinished uploads older than the abandoned upload age
+	// in the buckets written by the manager, and returns the number of uploads aborted.
+	CleanUploads() (aborted int, err error)
+}
+
+// MarkerListerI is implemented by the managers whose backend continues the listings
+// from its own markers instead of from an object path.
+type MarkerListerI interface {
+	// ListPage returns up to limit objects in the bucket with the prefix starting from the marker
+	// (empty to start from the beginning), and the marker of the next page, empty in the last one.
+	ListPage(bucket string, prefix string, marker string, limit int) (objs []ObjectT, nextMarker string, err error)
+}
+
+// ServerCopierI is implemented by the managers able to copy the objects inside the backend.
+type ServerCopierI interface {
+	// CanCopyFrom returns true when the objects of the source manager can be copied
+	// inside the backend by this one, as both use the same provider, endpoint and credentials.
+	CanCopyFrom(source ObjectManagerI) bool
+	// CopyObject copies the source object to the destination inside the backend,
+	// keeping its content type and md5, or returns ErrObjectNotFound when it does not exist.
+	CopyObject(src ObjectT, dst ObjectT) error
 }
 
 type ObjectI interface {
 	io.ReadCloser
 	GetContentType() string
 	GetSize() int64
+	// GetMD5String returns the content md5, or empty when the source does not know it.
 	GetMD5String() string
+	// GetChecksum returns the best content checksum known by the source and its algorithm,
+	// or empty when the source does not know any.
+	GetChecksum() (algorithm string, checksum string)
+}
+
+// ObjectInfoT stores the object attributes, with the checksums hex encoded
+// and empty when the source does not have them (the md5 is empty when
+// the source only has the etag of an object uploaded in multiple parts).
+type ObjectInfoT struct {
+	ContentType string
+	Size        int64
+	MD5         string
+	CRC32C      string
+	SHA256      string
+}
+
+// GetChecksum returns the object checksum for the algorithm, empty when there is not.
+func (i *ObjectInfoT) GetChecksum(algorithm string) string {
+	switch algorithm {
+	case ChecksumAlgorithmMD5:
+		return i.MD5
+	case ChecksumAlgorithmCRC32C:
+		return i.CRC32C
+	case ChecksumAlgorithmSHA256:
+		return i.SHA256
+	}
+
+	return ""
+}
+
+// GetSourceChecksum returns the first object checksum available by preference:
+// md5, sha256 and crc32c.
+func (i *ObjectInfoT) GetSourceChecksum() (algorithm string, checksum string) {
+	for _, algorithm = range []string{ChecksumAlgorithmMD5, ChecksumAlgorithmSHA256, ChecksumAlgorithmCRC32C} {
+		if checksum = i.GetChecksum(algorithm); checksum != "" {
+			return algorithm, checksum
+		}
+	}
+
+	return "", ""
 }
 
 type ObjectT struct {
@@ -29,14 +121,35 @@ type ObjectT struct {
 
 func GetManager(ctx context.Context, config v1alpha3.SourceConfigT) (m ObjectManagerI, err error) {
 	switch config.Type {
-	case "s3":
+	case TypeS3:
 		{
 			m = &S3ManagerT{}
 		}
-	case "gcs":
+	case TypeGCS:
 		{
 			m = &GCSManagerT{}
 		}
+	case TypeAzure:
+		{
+			m = &AzureManagerT{}
+		}
+	case TypeFilesystem:
+		{
+			m = &FilesystemManagerT{}
+		}
+	case TypeMemory:
+		{
+			m = &MemoryManagerT{}
+		}
+	case TypeHTTP:
+		{
+			m = &HTTPManagerT{}
+		}
+	default:
+		{
+			err = fmt.Errorf("object storage source type '%s' not supported", config.Type)
+			return m, err
+		}
 	}
 	err = m.Init(ctx, config)
 	return m, err
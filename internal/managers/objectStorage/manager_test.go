@@ -0,0 +1,151 @@
+package objectStorage
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"bot/api/v1alpha3"
+)
+
+var testListPaths = []string{"c", "a/b/2", "a-c", "b/1", "a/c", "a/b/1"}
+
+// getTestManagers returns the managers listing in order, with the test paths put in the bucket.
+func getTestManagers(t *testing.T, bucket string) (managers map[string]ObjectManagerI) {
+	t.Helper()
+
+	managers = map[string]ObjectManagerI{
+		TypeMemory:     &MemoryManagerT{},
+		TypeFilesystem: &FilesystemManagerT{},
+	}
+	for sourceType, manager := range managers {
+		err := manager.Init(context.Background(), v1alpha3.SourceConfigT{
+			Name:       t.Name() + "/" + sourceType,
+			Type:       sourceType,
+			Filesystem: v1alpha3.FilesystemT{Directory: t.TempDir()},
+		})
+		if err != nil {
+			t.Fatalf("unable to init %s manager: %s", sourceType, err.Error())
+		}
+
+		for _, objPath := range testListPaths {
+			err = manager.PutObject(ObjectT{Bucket: bucket, Path: objPath}, newTestObject([]byte(objPath)))
+			if err != nil {
+				t.Fatalf("unable to put object '%s' in %s manager: %s", objPath, sourceType, err.Error())
+			}
+		}
+	}
+
+	return managers
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		startAfter string
+		limit      int
+
+		expected []string
+	}{
+		{
+			name:     "all objects in path order",
+			limit:    100,
+			expected: []string{"a-c", "a/b/1", "a/b/2", "a/c", "b/1", "c"},
+		},
+		{
+			name:     "limit",
+			limit:    2,
+			expected: []string{"a-c", "a/b/1"},
+		},
+		{
+			name:     "prefix",
+			prefix:   "a/",
+			limit:    100,
+			expected: []string{"a/b/1", "a/b/2", "a/c"},
+		},
+		{
+			name:       "start after object in directory",
+			startAfter: "a/b/1",
+			limit:      100,
+			expected:   []string{"a/b/2", "a/c", "b/1", "c"},
+		},
+		{
+			name:       "start after last object in directory",
+			startAfter: "a/b/2",
+			limit:      100,
+			expected:   []string{"a/c", "b/1", "c"},
+		},
+		{
+			name:       "start after directory path",
+			startAfter: "a/b/",
+			limit:      100,
+			expected:   []string{"a/b/1", "a/b/2", "a/c", "b/1", "c"},
+		},
+		{
+			name:       "prefix and start after",
+			prefix:     "a/",
+			startAfter: "a/b/2",
+			limit:      100,
+			expected:   []string{"a/c"},
+		},
+	}
+
+	managers := getTestManagers(t, "bucket")
+	for sourceType, manager := range managers {
+		for _, test := range tests {
+			t.Run(sourceType+"/"+test.name, func(t *testing.T) {
+				objs, err := manager.List("bucket", test.prefix, test.startAfter, test.limit)
+				if err != nil {
+					t.Fatalf("unable to list objects: %s", err.Error())
+				}
+
+				paths := []string{}
+				for _, obj := range objs {
+					paths = append(paths, obj.Path)
+				}
+				if !slices.Equal(paths, test.expected) {
+					t.Fatalf("listed %v, expected %v", paths, test.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestGetObject(t *testing.T) {
+	managers := getTestManagers(t, "bucket")
+	for sourceType, manager := range managers {
+		t.Run(sourceType, func(t *testing.T) {
+			ro, err := manager.GetObject(ObjectT{Bucket: "bucket", Path: "a/b/1"})
+			if err != nil {
+				t.Fatalf("unable to get object: %s", err.Error())
+			}
+			defer ro.Close()
+
+			content := new(bytes.Buffer)
+			if _, err = content.ReadFrom(ro); err != nil {
+				t.Fatalf("unable to read object: %s", err.Error())
+			}
+			if content.String() != "a/b/1" || ro.GetSize() != int64(len("a/b/1")) {
+				t.Fatalf("object content is '%s' with size %d, expected 'a/b/1'", content.String(), ro.GetSize())
+			}
+
+			_, err = manager.GetObject(ObjectT{Bucket: "bucket", Path: "a/b"})
+			if err != ErrObjectNotFound {
+				t.Fatalf("get missing object error is '%v', expected '%v'", err, ErrObjectNotFound)
+			}
+		})
+	}
+}
+
+// newTestObject returns a memory object with the content.
+func newTestObject(content []byte) ObjectI {
+	return &MemoryObjectT{
+		reader: bytes.NewReader(content),
+		info: ObjectInfoT{
+			ContentType: "application/octet-stream",
+			Size:        int64(len(content)),
+		},
+	}
+}
@@ -0,0 +1,165 @@
+package objectStorage
+
+import (
+	"bot/api/v1alpha3"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	// memoryStores are the in memory objects by source name, shared by the managers
+	// of the same source in the object and job workers
+	memoryStores   = map[string]*memoryStoreT{}
+	memoryStoresMu sync.Mutex
+)
+
+// MemoryManagerT stores the objects in memory, lost when the process ends.
+// It is intended for tests without a cloud backend.
+type MemoryManagerT struct {
+	ctx   context.Context
+	store *memoryStoreT
+}
+
+type MemoryObjectT struct {
+	reader *bytes.Reader
+	info   ObjectInfoT
+}
+
+type memoryStoreT struct {
+	mu sync.RWMutex
+	// objects are the object contents and attributes by bucket and path
+	objects map[string]map[string]memoryObjectDataT
+}
+
+type memoryObjectDataT struct {
+	content []byte
+	info    ObjectInfoT
+}
+
+func (m *MemoryManagerT) Init(ctx context.Context, config v1alpha3.SourceConfigT) (err error) {
+	m.ctx = ctx
+
+	memoryStoresMu.Lock()
+	defer memoryStoresMu.Unlock()
+
+	store, ok := memoryStores[config.Name]
+	if !ok {
+		store = &memoryStoreT{
+			objects: map[string]map[string]memoryObjectDataT{},
+		}
+		memoryStores[config.Name] = store
+	}
+	m.store = store
+
+	return err
+}
+
+func (m *MemoryManagerT) GetObject(obj ObjectT) (ro ObjectI, err error) {
+	data, err := m.getObjectData(obj)
+	if err != nil {
+		return ro, err
+	}
+
+	memobji := &MemoryObjectT{}
+	memobji.reader = bytes.NewReader(data.content)
+	memobji.info = data.info
+
+	ro = memobji
+	return ro, err
+}
+
+func (m *MemoryManagerT) PutObject(obj ObjectT, ro ObjectI) (err error) {
+	content, err := io.ReadAll(ro)
+	if err != nil {
+		return err
+	}
+
+	md5sum := md5.Sum(content)
+	data := memoryObjectDataT{
+		content: content,
+		info: ObjectInfoT{
+			ContentType: ro.GetContentType(),
+			Size:        int64(len(content)),
+			MD5:         hex.EncodeToString(md5sum[:]),
+		},
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if _, ok := m.store.objects[obj.Bucket]; !ok {
+		m.store.objects[obj.Bucket] = map[string]memoryObjectDataT{}
+	}
+	m.store.objects[obj.Bucket][obj.Path] = data
+
+	return err
+}
+
+func (m *MemoryManagerT) StatObject(obj ObjectT) (info ObjectInfoT, err error) {
+	data, err := m.getObjectData(obj)
+	return data.info, err
+}
+
+func (m *MemoryManagerT) List(bucket string, prefix string, startAfter string, limit int) (objs []ObjectT, err error) {
+	m.store.mu.RLock()
+	paths := []string{}
+	for objPath := range m.store.objects[bucket] {
+		if strings.HasPrefix(objPath, prefix) && objPath > startAfter {
+			paths = append(paths, objPath)
+		}
+	}
+	m.store.mu.RUnlock()
+
+	slices.Sort(paths)
+	for _, objPath := range paths[:min(limit, len(paths))] {
+		objs = append(objs, ObjectT{
+			Bucket: bucket,
+			Path:   objPath,
+		})
+	}
+
+	return objs, err
+}
+
+func (m *MemoryManagerT) getObjectData(obj ObjectT) (data memoryObjectDataT, err error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	data, ok := m.store.objects[obj.Bucket][obj.Path]
+	if !ok {
+		err = ErrObjectNotFound
+	}
+
+	return data, err
+}
+
+func (o *MemoryObjectT) GetContentType() string {
+	return o.info.ContentType
+}
+
+func (o *MemoryObjectT) GetSize() int64 {
+	return o.info.Size
+}
+
+func (o *MemoryObjectT) GetMD5String() string {
+	return o.info.MD5
+}
+
+func (o *MemoryObjectT) GetChecksum() (algorithm string, checksum string) {
+	return o.info.GetSourceChecksum()
+}
+
+func (o *MemoryObjectT) Read(p []byte) (n int, err error) {
+	n, err = o.reader.Read(p)
+	return n, err
+}
+
+func (o *MemoryObjectT) Close() error {
+	return nil
+}
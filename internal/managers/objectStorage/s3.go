@@ -4,6 +4,8 @@ import (
 	"bot/api/v1alpha3"
 	"context"
 	"io"
+	"strings"
+	"sync"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -12,15 +14,31 @@ import (
 type S3ManagerT struct {
 	ctx    context.Context
 	client *minio.Client
+	core   *minio.Core
+	upload v1alpha3.UploadConfigT
+
+	// accessKeyID identifies the credentials, as the server-side copies
+	// read the source with the destination credentials
+	accessKeyID string
+
+	// buckets are the buckets written, where the abandoned uploads are cleaned, and uploads
+	// are the resumable uploads started by the manager by object bucket and path
+	mu      sync.Mutex
+	buckets map[string]struct{}
+	uploads map[string]s3UploadT
 }
 
 type S3ObjectT struct {
-	reader      io.ReadCloser
-	md5Sum      string
-	size        int64
-	contentType string
+	reader io.ReadCloser
+	info   ObjectInfoT
 }
 
+var (
+	// s3MD5MetadataKeys are the user metadata keys where the MD5 of the objects
+	// uploaded in multiple parts is usually stored by the uploaders
+	s3MD5MetadataKeys = []string{"md5", "md5sum", "content-md5"}
+)
+
 func (m *S3ManagerT) Init(ctx context.Context, config v1alpha3.SourceConfigT) (err error) {
 	m.ctx = ctx
 	m.client, err = minio.New(
@@ -31,12 +49,21 @@ func (m *S3ManagerT) Init(ctx context.Context, config v1alpha3.SourceConfigT) (e
 			Secure: config.S3.Secure,
 		},
 	)
+	if err != nil {
+		return err
+	}
+
+	m.core = &minio.Core{Client: m.client}
+	m.accessKeyID = config.S3.AccessKeyID
+	m.upload = config.Upload
+	m.buckets = map[string]struct{}{}
+	m.uploads = map[string]s3UploadT{}
 
 	return err
 }
 
 func (m *S3ManagerT) GetObject(obj ObjectT) (ro ObjectI, err error) {
-	stat, err := m.client.StatObject(m.ctx, obj.Bucket, obj.Path, minio.StatObjectOptions{})
+	info, err := m.StatObject(obj)
 	if err != nil {
 		return ro, err
 	}
@@ -48,35 +75,168 @@ func (m *S3ManagerT) GetObject(obj ObjectT) (ro ObjectI, err error) {
 
 	s3obji := &S3ObjectT{}
 	s3obji.reader = s3obj
-	s3obji.md5Sum = stat.ETag
-	s3obji.size = stat.Size
-	s3obji.contentType = stat.ContentType
+	s3obji.info = info
 
 	ro = s3obji
 	return ro, err
 }
 
 func (m *S3ManagerT) PutObject(obj ObjectT, ro ObjectI) (err error) {
-	_, err = m.client.PutObject(m.ctx, obj.Bucket, obj.Path, ro, ro.GetSize(), minio.PutObjectOptions{
+	opts := minio.PutObjectOptions{
 		ContentType: ro.GetContentType(),
+	}
+
+	// keep the md5 in the user metadata, as the etag is not the md5
+	// when the object is uploaded in multiple parts
+	if md5 := ro.GetMD5String(); md5 != "" {
+		opts.UserMetadata = map[string]string{"md5": md5}
+	}
+
+	m.addBucket(obj.Bucket)
+
+	// the objects bigger than a part, or with unknown size, are uploaded in parts
+	if ro.GetSize() < 0 || ro.GetSize() > m.upload.PartSize {
+		return m.putMultipartObject(obj, ro, opts)
+	}
+
+	_, err = m.client.PutObject(m.ctx, obj.Bucket, obj.Path, ro, ro.GetSize(), opts)
+	return err
+}
+
+func (m *S3ManagerT) StatObject(obj ObjectT) (info ObjectInfoT, err error) {
+	opts := minio.StatObjectOptions{
+		Checksum: true,
+	}
+	stat, err := m.client.StatObject(m.ctx, obj.Bucket, obj.Path, opts)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			err = ErrObjectNotFound
+		}
+		return info, err
+	}
+
+	info.ContentType = stat.ContentType
+	info.Size = stat.Size
+	info.MD5 = getS3ObjectMD5(stat)
+	info.CRC32C = base64ToHex(stat.ChecksumCRC32C)
+	info.SHA256 = base64ToHex(stat.ChecksumSHA256)
+
+	return info, err
+}
+
+func (m *S3ManagerT) List(bucket string, prefix string, startAfter string, limit int) (objs []ObjectT, err error) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	objsCh := m.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+		Prefix:     prefix,
+		StartAfter: startAfter,
+		Recursive:  true,
+		MaxKeys:    limit,
 	})
+	for objInfo := range objsCh {
+		if objInfo.Err != nil {
+			return objs, objInfo.Err
+		}
+
+		objs = append(objs, ObjectT{
+			Bucket: bucket,
+			Path:   objInfo.Key,
+		})
+		if len(objs) >= limit {
+			break
+		}
+	}
+
+	return objs, err
+}
+
+func (m *S3ManagerT) CanCopyFrom(source ObjectManagerI) bool {
+	s3source, ok := source.(*S3ManagerT)
+	return ok && s3source.client.EndpointURL().String() == m.client.EndpointURL().String() &&
+		s3source.accessKeyID == m.accessKeyID
+}
+
+// CopyObject copies the object with CopyObject, or with UploadPartCopy when it is bigger
+// than the max copy size. The metadata is set in the destination as the multipart copies
+// do not keep it, with the md5 as the etag of the multipart copies is not the md5.
+func (m *S3ManagerT) CopyObject(src ObjectT, dst ObjectT) (err error) {
+	stat, err := m.client.StatObject(m.ctx, src.Bucket, src.Path, minio.StatObjectOptions{})
 	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			err = ErrObjectNotFound
+		}
 		return err
 	}
 
+	userMetadata := map[string]string{"Content-Type": stat.ContentType}
+	for key, value := range stat.UserMetadata {
+		userMetadata[key] = value
+	}
+	if md5 := getS3ObjectMD5(stat); md5 != "" {
+		userMetadata["md5"] = md5
+	}
+
+	m.addBucket(dst.Bucket)
+
+	// the etag condition fails the copy when the source changes in the middle
+	_, err = m.client.ComposeObject(m.ctx,
+		minio.CopyDestOptions{
+			Bucket:          dst.Bucket,
+			Object:          dst.Path,
+			UserMetadata:    userMetadata,
+			ReplaceMetadata: true,
+		},
+		minio.CopySrcOptions{
+			Bucket:    src.Bucket,
+			Object:    src.Path,
+			MatchETag: stat.ETag,
+		},
+	)
+
 	return err
 }
 
+// getS3ObjectMD5 returns the object md5, taken from the etag or from the user metadata
+// when the object was uploaded in multiple parts, or empty when it is unknown.
+func getS3ObjectMD5(stat minio.ObjectInfo) (md5 string) {
+	md5 = strings.ToLower(strings.Trim(stat.ETag, `"`))
+	if IsMD5(md5) {
+		return md5
+	}
+
+	for key, value := range stat.UserMetadata {
+		for _, md5Key := range s3MD5MetadataKeys {
+			if !strings.EqualFold(key, md5Key) {
+				continue
+			}
+
+			if md5 = strings.ToLower(value); IsMD5(md5) {
+				return md5
+			}
+			if md5 = base64ToHex(value); IsMD5(md5) {
+				return md5
+			}
+		}
+	}
+
+	return ""
+}
+
 func (o *S3ObjectT) GetContentType() string {
-	return o.contentType
+	return o.info.ContentType
 }
 
 func (o *S3ObjectT) GetSize() int64 {
-	return o.size
+	return o.info.Size
 }
 
 func (o *S3ObjectT) GetMD5String() string {
-	return o.md5Sum
+	return o.info.MD5
+}
+
+func (o *S3ObjectT) GetChecksum() (algorithm string, checksum string) {
+	return o.info.GetSourceChecksum()
 }
 
 func (o *S3ObjectT) Read(p []byte) (n int, err error) {
@@ -0,0 +1,59 @@
+package objectStorage
+
+import (
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestGetS3ObjectMD5(t *testing.T) {
+	tests := []struct {
+		name string
+		stat minio.ObjectInfo
+
+		expected string
+	}{
+		{
+			name:     "single part etag",
+			stat:     minio.ObjectInfo{ETag: `"25F9E794323B453885F5181F1B624D0B"`},
+			expected: "25f9e794323b453885f5181f1b624d0b",
+		},
+		{
+			name:     "multipart etag without metadata",
+			stat:     minio.ObjectInfo{ETag: `"d41d8cd98f00b204e9800998ecf8427e-2"`},
+			expected: "",
+		},
+		{
+			name: "multipart etag with hex metadata",
+			stat: minio.ObjectInfo{
+				ETag:         `"d41d8cd98f00b204e9800998ecf8427e-2"`,
+				UserMetadata: map[string]string{"Md5sum": "25f9e794323b453885f5181f1b624d0b"},
+			},
+			expected: "25f9e794323b453885f5181f1b624d0b",
+		},
+		{
+			name: "multipart etag with base64 metadata",
+			stat: minio.ObjectInfo{
+				ETag:         `"d41d8cd98f00b204e9800998ecf8427e-2"`,
+				UserMetadata: map[string]string{"Content-Md5": "JfnnlDI7RTiF9RgfG2JNCw=="},
+			},
+			expected: "25f9e794323b453885f5181f1b624d0b",
+		},
+		{
+			name: "multipart etag with invalid metadata",
+			stat: minio.ObjectInfo{
+				ETag:         `"d41d8cd98f00b204e9800998ecf8427e-2"`,
+				UserMetadata: map[string]string{"Md5": "not-a-md5", "Other": "25f9e794323b453885f5181f1b624d0b"},
+			},
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if md5 := getS3ObjectMD5(test.stat); md5 != test.expected {
+				t.Fatalf("md5 is '%s', expected '%s'", md5, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package objectStorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// s3MinPartSize, s3MaxParts and s3MaxObjectSize are the multipart upload limits of S3
+	s3MinPartSize   = 5 * 1024 * 1024
+	s3MaxParts      = 10000
+	s3MaxObjectSize = 5 * 1024 * 1024 * 1024 * 1024
+)
+
+// s3UploadT is an unfinished upload started by the manager, with the fingerprint
+// of the options it was started with.
+type s3UploadT struct {
+	id          string
+	fingerprint string
+}
+
+// putMultipartObject uploads the object in parts, with up to the upload concurrency parts
+// uploaded at the same time. When the uploads are resumable, the parts already uploaded
+// by an interrupted upload of the object with the same options are not uploaded again when
+// their content is the same, and the upload is kept on failure to be resumed in the next attempt.
+// Otherwise it is aborted.
+func (m *S3ManagerT) putMultipartObject(obj ObjectT, ro ObjectI, opts minio.PutObjectOptions) (err error) {
+	fingerprint := getUploadFingerprint(opts)
+
+	uploadId := ""
+	uploadedParts := map[int]minio.ObjectPart{}
+	if m.upload.Resumable {
+		uploadId, uploadedParts, err = m.getResumableUpload(obj, fingerprint)
+		if err != nil {
+			return err
+		}
+	}
+
+	if uploadId == "" {
+		uploadId, err = m.core.NewMultipartUpload(m.ctx, obj.Bucket, obj.Path, opts)
+		if err != nil {
+			return err
+		}
+
+		if m.upload.Resumable {
+			m.setUpload(obj, s3UploadT{id: uploadId, fingerprint: fingerprint})
+		}
+	}
+
+	parts, err := m.putParts(obj, uploadId, ro, m.getPartSize(ro.GetSize()), uploadedParts)
+	if err != nil {
+		if !m.upload.Resumable {
+			abortErr := m.core.AbortMultipartUpload(m.ctx, obj.Bucket, obj.Path, uploadId)
+			if abortErr != nil {
+				err = fmt.Errorf("%w (unable to abort multipart upload: %s)", err, abortErr.Error())
+			}
+		}
+		return err
+	}
+
+	_, err = m.core.CompleteMultipartUpload(m.ctx, obj.Bucket, obj.Path, uploadId, parts, opts)
+	if err == nil {
+		m.deleteUpload(obj)
+	}
+	return err
+}
+
+// putParts reads the object in parts and uploads them, skipping the uploaded parts with the same
+// size and md5, and returns the parts to complete the upload in part number order.
+func (m *S3ManagerT) putParts(obj ObjectT, uploadId string, ro io.Reader, partSize int64,
+	uploadedParts map[int]minio.ObjectPart) (parts []minio.CompletePart, err error) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	var putErr error
+
+	// the slots bound the parts in memory to the ones being uploaded and the one being read
+	slots := make(chan struct{}, m.upload.Concurrency)
+	for partNumber := 1; ; partNumber++ {
+		slots <- struct{}{}
+
+		data := make([]byte, partSize)
+		n, eof, readErr := readPart(ro, data)
+		if readErr != nil {
+			<-slots
+			err = readErr
+			break
+		}
+
+		// the empty objects are uploaded in an empty part
+		if n == 0 && partNumber > 1 {
+			<-slots
+			break
+		}
+		data = data[:n]
+		md5sum := md5.Sum(data)
+
+		if part, ok := uploadedParts[partNumber]; ok && part.Size == int64(n) &&
+			strings.Trim(part.ETag, `"`) == hex.EncodeToString(md5sum[:]) {
+			mu.Lock()
+			parts = append(parts, minio.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+			mu.Unlock()
+			<-slots
+		} else {
+			wg.Add(1)
+			go func(partNumber int, data []byte, md5sum [md5.Size]byte) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				part, err := m.core.PutObjectPart(ctx, obj.Bucket, obj.Path, uploadId, partNumber,
+					bytes.NewReader(data), int64(len(data)), minio.PutObjectPartOptions{
+						Md5Base64: base64.StdEncoding.EncodeToString(md5sum[:]),
+					})
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if putErr == nil {
+						putErr = fmt.Errorf("unable to upload part %d: %w", partNumber, err)
+						cancel()
+					}
+					return
+				}
+				parts = append(parts, minio.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+			}(partNumber, data, md5sum)
+		}
+
+		mu.Lock()
+		failed := putErr != nil
+		mu.Unlock()
+		if failed || eof {
+			break
+		}
+	}
+
+	wg.Wait()
+	if err == nil {
+		err = putErr
+	}
+
+	slices.SortFunc(parts, func(a, b minio.CompletePart) int {
+		return a.PartNumber - b.PartNumber
+	})
+
+	return parts, err
+}
+
+// readPart fills the part with the reader content, returning the bytes read and if the
+// content ended. Unlike io.ReadFull, the io.ErrUnexpectedEOF of a truncated reader
+// is an error and not the end of the content.
+func readPart(r io.Reader, part []byte) (n int, eof bool, err error) {
+	for n < len(part) {
+		var read int
+		read, err = r.Read(part[n:])
+		n += read
+		if err == io.EOF {
+			return n, true, nil
+		}
+		if err != nil {
+			return n, false, err
+		}
+	}
+
+	return n, false, err
+}
+
+// getResumableUpload returns the unfinished upload of the object started by the manager
+// with the same options fingerprint and its uploaded parts by part number, or an empty upload id
+// when there is none. The rest of unfinished uploads of the object are aborted, as the options
+// they were started with, like the metadata, are unknown or different.
+func (m *S3ManagerT) getResumableUpload(obj ObjectT, fingerprint string) (uploadId string, parts map[int]minio.ObjectPart, err error) {
+	parts = map[int]minio.ObjectPart{}
+
+	uploads, err := m.core.ListMultipartUploads(m.ctx, obj.Bucket, obj.Path, "", "", "", 1000)
+	if err != nil {
+		return uploadId, parts, err
+	}
+
+	started, found := m.getUpload(obj)
+	for _, upload := range uploads.Uploads {
+		if upload.Key != obj.Path {
+			continue
+		}
+
+		if found && upload.UploadID == started.id && started.fingerprint == fingerprint {
+			uploadId = upload.UploadID
+			continue
+		}
+
+		err = m.core.AbortMultipartUpload(m.ctx, obj.Bucket, obj.Path, upload.UploadID)
+		if err != nil && minio.ToErrorResponse(err).Code != "NoSuchUpload" {
+			return "", parts, fmt.Errorf("unable to abort previous upload '%s': %w", upload.UploadID, err)
+		}
+		err = nil
+	}
+
+	if uploadId == "" {
+		m.deleteUpload(obj)
+		return uploadId, parts, err
+	}
+
+	marker := 0
+	for {
+		result, err := m.core.ListObjectParts(m.ctx, obj.Bucket, obj.Path, uploadId, marker, 1000)
+		if err != nil {
+			// the upload can be finished or aborted after listing it
+			if minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+				m.deleteUpload(obj)
+				return "", map[int]minio.ObjectPart{}, nil
+			}
+			return uploadId, parts, err
+		}
+
+		for _, part := range result.ObjectParts {
+			parts[part.PartNumber] = part
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+
+	return uploadId, parts, err
+}
+
+// getUploadFingerprint returns the hash of the headers set by the upload options,
+// to resume only the uploads started with the same ones.
+func getUploadFingerprint(opts minio.PutObjectOptions) string {
+	header := opts.Header()
+
+	keys := []string{}
+	for key := range header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s: %s\n", key, strings.Join(header[key], ","))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// getPartSize returns the configured part size, or the one fitting the object
+// in the max number of parts when it is bigger. The objects with unknown size
+// take the part size fitting the max object size.
+func (m *S3ManagerT) getPartSize(size int64) int64 {
+	if size < 0 {
+		size = s3MaxObjectSize
+	}
+
+	partSize := max(m.upload.PartSize, s3MinPartSize)
+	if size > partSize*s3MaxParts {
+		partSize = (size + s3MaxParts - 1) / s3MaxParts
+	}
+	return partSize
+}
+
+// CleanUploads aborts the unfinished uploads older than the abandoned upload age
+// in the buckets of the backend, or in the ones written by the manager when
+// the credentials can not list the buckets.
+func (m *S3ManagerT) CleanUploads() (aborted int, err error) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+
+	errs := []error{}
+	buckets := m.getBuckets()
+	listed, err := m.client.ListBuckets(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("unable to list buckets, cleaning only the written ones: %w", err))
+	}
+	for _, bucket := range listed {
+		if !slices.Contains(buckets, bucket.Name) {
+			buckets = append(buckets, bucket.Name)
+		}
+	}
+
+	before := time.Now().Add(-m.upload.AbandonedUploadAge)
+	for _, bucket := range buckets {
+		for upload := range m.client.ListIncompleteUploads(ctx, bucket, "", true) {
+			if upload.Err != nil {
+				errs = append(errs, fmt.Errorf("unable to list unfinished uploads in bucket '%s': %w", bucket, upload.Err))
+				break
+			}
+
+			if upload.Initiated.After(before) {
+				continue
+			}
+
+			err = m.core.AbortMultipartUpload(ctx, bucket, upload.Key, upload.UploadID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to abort upload of object '%s' in bucket '%s': %w", upload.Key, bucket, err))
+				continue
+			}
+			m.deleteUpload(ObjectT{Bucket: bucket, Path: upload.Key})
+			aborted++
+		}
+	}
+
+	return aborted, errors.Join(errs...)
+}
+
+func (m *S3ManagerT) addBucket(bucket string) {
+	m.mu.Lock()
+	m.buckets[bucket] = struct{}{}
+	m.mu.Unlock()
+}
+
+func (m *S3ManagerT) getBuckets() (buckets []string) {
+	m.mu.Lock()
+	for bucket := range m.buckets {
+		buckets = append(buckets, bucket)
+	}
+	m.mu.Unlock()
+
+	return buckets
+}
+
+func (m *S3ManagerT) setUpload(obj ObjectT, upload s3UploadT) {
+	m.mu.Lock()
+	m.uploads[obj.Bucket+"/"+obj.Path] = upload
+	m.mu.Unlock()
+}
+
+func (m *S3ManagerT) getUpload(obj ObjectT) (upload s3UploadT, found bool) {
+	m.mu.Lock()
+	upload, found = m.uploads[obj.Bucket+"/"+obj.Path]
+	m.mu.Unlock()
+
+	return upload, found
+}
+
+func (m *S3ManagerT) deleteUpload(obj ObjectT) {
+	m.mu.Lock()
+	delete(m.uploads, obj.Bucket+"/"+obj.Path)
+	m.mu.Unlock()
+}
@@ -0,0 +1,109 @@
+package objectStorage
+
+import (
+	"testing"
+
+	"bot/api/v1alpha3"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestGetPartSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		partSize int64
+		size     int64
+
+		expected int64
+	}{
+		{
+			name:     "configured part size",
+			partSize: 16 * 1024 * 1024,
+			size:     100 * 1024 * 1024,
+			expected: 16 * 1024 * 1024,
+		},
+		{
+			name:     "part size below the min part size",
+			partSize: 1024,
+			size:     100 * 1024 * 1024,
+			expected: s3MinPartSize,
+		},
+		{
+			name:     "object bigger than the max parts",
+			partSize: s3MinPartSize,
+			size:     s3MinPartSize*s3MaxParts + 1,
+			expected: s3MinPartSize + 1,
+		},
+		{
+			name:     "unknown size",
+			partSize: s3MinPartSize,
+			size:     -1,
+			expected: (s3MaxObjectSize + s3MaxParts - 1) / s3MaxParts,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &S3ManagerT{upload: v1alpha3.UploadConfigT{PartSize: test.partSize}}
+
+			partSize := m.getPartSize(test.size)
+			if partSize != test.expected {
+				t.Fatalf("part size is %d, expected %d", partSize, test.expected)
+			}
+
+			size := test.size
+			if size < 0 {
+				size = s3MaxObjectSize
+			}
+			if parts := (size + partSize - 1) / partSize; parts > s3MaxParts {
+				t.Fatalf("object uploaded in %d parts, expected at most %d", parts, s3MaxParts)
+			}
+		})
+	}
+}
+
+func TestGetUploadFingerprint(t *testing.T) {
+	opts := minio.PutObjectOptions{
+		ContentType:  "text/plain",
+		UserMetadata: map[string]string{"md5": "d41d8cd98f00b204e9800998ecf8427e", "owner": "bot"},
+	}
+
+	tests := []struct {
+		name  string
+		other minio.PutObjectOptions
+
+		expectedSame bool
+	}{
+		{
+			name: "same options",
+			other: minio.PutObjectOptions{
+				ContentType:  "text/plain",
+				UserMetadata: map[string]string{"owner": "bot", "md5": "d41d8cd98f00b204e9800998ecf8427e"},
+			},
+			expectedSame: true,
+		},
+		{
+			name: "other metadata",
+			other: minio.PutObjectOptions{
+				ContentType:  "text/plain",
+				UserMetadata: map[string]string{"md5": "d41d8cd98f00b204e9800998ecf8427e", "owner": "other"},
+			},
+		},
+		{
+			name: "other content type",
+			other: minio.PutObjectOptions{
+				ContentType:  "application/json",
+				UserMetadata: map[string]string{"md5": "d41d8cd98f00b204e9800998ecf8427e", "owner": "bot"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			same := getUploadFingerprint(opts) == getUploadFingerprint(test.other)
+			if same != test.expectedSame {
+				t.Fatalf("same fingerprint is %t, expected %t", same, test.expectedSame)
+			}
+		})
+	}
+}
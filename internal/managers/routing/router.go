@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"bot/api/v1alpha3"
+	"bot/internal/managers/objectStorage"
+)
+
+const (
+	PolicyOverwrite       = "overwrite"
+	PolicySkipIfExists    = "skipIfExists"
+	PolicySkipIfIdentical = "skipIfIdentical"
+)
+
+type RouterT struct {
+	config *v1alpha3.ObjectWorkerConfigT
+}
+
+func NewRouter(config *v1alpha3.ObjectWorkerConfigT) (r *RouterT) {
+	r = &RouterT{
+		config: config,
+	}
+
+	return r
+}
+
+// GetRoute returns the route configured for the requested object
+// depending on the routing type.
+func (r *RouterT) GetRoute(object objectStorage.ObjectT) (route v1alpha3.RouteConfigT, found bool) {
+	switch r.config.Routing.Type {
+	case "bucket":
+		{
+			route, found = r.config.Routing.Routes[object.Bucket]
+		}
+	case "metadata":
+		{
+			conditionKey := object.Metadata.Get(r.config.Routing.MetadataKey)
+			route, found = r.config.Routing.Routes[conditionKey]
+		}
+	}
+
+	return route, found
+}
+
+func (r *RouterT) GetBackendObject(object objectStorage.ObjectT) (backend objectStorage.ObjectT, source string, err error) {
+	route, found := r.GetRoute(object)
+	if !found {
+		return backend, source, fmt.Errorf("not route for backend object")
+	}
+
+	source = route.Backend.Source
+	backend = r.applyModifiers(object, route.Backend.Modifiers)
+
+	if backend.Bucket == "" || backend.Path == "" {
+		err = fmt.Errorf("empty bucket or path object, check modifiers")
+	}
+
+	return backend, source, err
+}
+
+func (r *RouterT) GetFrontendObject(object objectStorage.ObjectT) (frontend objectStorage.ObjectT, source string, err error) {
+	route, found := r.GetRoute(object)
+	if !found {
+		return frontend, source, fmt.Errorf("not route for frontend object")
+	}
+
+	source = route.Front.Source
+	frontend = r.applyModifiers(object, route.Front.Modifiers)
+
+	if frontend.Bucket == "" || frontend.Path == "" {
+		err = fmt.Errorf("empty bucket or path object, check modifiers")
+	}
+
+	return frontend, source, err
+}
+
+// GetRequestObject returns the requested object routed to the backend object, with the request
+// bucket and metadata of the object, reversing the path prefixes of the route backend modifiers.
+// It is used to request the objects listed in the backend, returned with the backend source.
+func (r *RouterT) GetRequestObject(object objectStorage.ObjectT, backend objectStorage.ObjectT) (request objectStorage.ObjectT, source string, err error) {
+	route, found := r.GetRoute(object)
+	if !found {
+		return request, source, fmt.Errorf("not route for backend object")
+	}
+
+	request = object
+	request.Path = backend.Path
+
+	// the backend path is defined by the last modifier of the route, as in applyModifiers
+	for _, modv := range route.Backend.Modifiers {
+		for _, modcongv := range r.config.Modifiers {
+			if modcongv.Name != modv {
+				continue
+			}
+
+			if !strings.HasPrefix(backend.Path, modcongv.AddPrefix) {
+				return request, source, fmt.Errorf("backend object path out of '%s' modifier prefix '%s'", modcongv.Name, modcongv.AddPrefix)
+			}
+			request.Path = modcongv.RemovePrefix + strings.TrimPrefix(backend.Path, modcongv.AddPrefix)
+		}
+	}
+
+	routed, source, err := r.GetBackendObject(request)
+	if err != nil {
+		return request, source, err
+	}
+
+	if routed.Bucket != backend.Bucket || routed.Path != backend.Path {
+		err = fmt.Errorf("backend object is not routed from the requested bucket, check modifiers")
+	}
+
+	return request, source, err
+}
+
+func (r *RouterT) applyModifiers(object objectStorage.ObjectT, modifiers []string) (result objectStorage.ObjectT) {
+	for _, modv := range modifiers {
+		for _, modcongv := range r.config.Modifiers {
+			if modcongv.Name == modv {
+				result.Bucket = modcongv.Bucket
+				result.Path = modcongv.AddPrefix + strings.TrimPrefix(object.Path, modcongv.RemovePrefix)
+			}
+		}
+	}
+
+	return result
+}
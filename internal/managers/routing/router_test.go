@@ -0,0 +1,162 @@
+package routing
+
+import (
+	"testing"
+
+	"bot/api/v1alpha3"
+	"bot/internal/managers/objectStorage"
+)
+
+func getTestRouter() *RouterT {
+	return NewRouter(&v1alpha3.ObjectWorkerConfigT{
+		Modifiers: []v1alpha3.ModifierConfigT{
+			{Name: "front", Bucket: "front-bucket"},
+			{Name: "backend", Bucket: "backend-bucket", AddPrefix: "backend/", RemovePrefix: "request/"},
+			{Name: "backend-plain", Bucket: "plain-bucket"},
+		},
+		Routing: v1alpha3.RoutingConfigT{
+			Type: "bucket",
+			Routes: map[string]v1alpha3.RouteConfigT{
+				"prefixed": {
+					Front:   v1alpha3.RouteObjConfigT{Source: "front-source", Modifiers: []string{"front"}},
+					Backend: v1alpha3.RouteObjConfigT{Source: "backend-source", Modifiers: []string{"backend"}},
+				},
+				"plain": {
+					Front:   v1alpha3.RouteObjConfigT{Source: "front-source", Modifiers: []string{"front"}},
+					Backend: v1alpha3.RouteObjConfigT{Source: "backend-source", Modifiers: []string{"backend-plain"}},
+				},
+			},
+		},
+	})
+}
+
+func TestGetBackendObject(t *testing.T) {
+	tests := []struct {
+		name   string
+		object objectStorage.ObjectT
+
+		expected    objectStorage.ObjectT
+		expectedErr bool
+	}{
+		{
+			name:     "prefix modifier",
+			object:   objectStorage.ObjectT{Bucket: "prefixed", Path: "request/path/object"},
+			expected: objectStorage.ObjectT{Bucket: "backend-bucket", Path: "backend/path/object"},
+		},
+		{
+			name:     "prefix modifier out of removed prefix",
+			object:   objectStorage.ObjectT{Bucket: "prefixed", Path: "path/object"},
+			expected: objectStorage.ObjectT{Bucket: "backend-bucket", Path: "backend/path/object"},
+		},
+		{
+			name:     "plain modifier",
+			object:   objectStorage.ObjectT{Bucket: "plain", Path: "path/object"},
+			expected: objectStorage.ObjectT{Bucket: "plain-bucket", Path: "path/object"},
+		},
+		{
+			name:        "not routed bucket",
+			object:      objectStorage.ObjectT{Bucket: "other", Path: "path/object"},
+			expectedErr: true,
+		},
+	}
+
+	r := getTestRouter()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backend, source, err := r.GetBackendObject(test.object)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected routing error, got %+v", backend)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to route object: %s", err.Error())
+			}
+
+			if source != "backend-source" {
+				t.Fatalf("source is '%s', expected 'backend-source'", source)
+			}
+			if backend.Bucket != test.expected.Bucket || backend.Path != test.expected.Path {
+				t.Fatalf("backend object is %s/%s, expected %s/%s",
+					backend.Bucket, backend.Path, test.expected.Bucket, test.expected.Path)
+			}
+		})
+	}
+}
+
+func TestGetRequestObject(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestBucket string
+		backend       objectStorage.ObjectT
+
+		expectedPath string
+		expectedErr  bool
+	}{
+		{
+			name:          "prefix modifier reversed",
+			requestBucket: "prefixed",
+			backend:       objectStorage.ObjectT{Bucket: "backend-bucket", Path: "backend/path/object"},
+			expectedPath:  "request/path/object",
+		},
+		{
+			name:          "plain modifier",
+			requestBucket: "plain",
+			backend:       objectStorage.ObjectT{Bucket: "plain-bucket", Path: "path/object"},
+			expectedPath:  "path/object",
+		},
+		{
+			name:          "backend path out of added prefix",
+			requestBucket: "prefixed",
+			backend:       objectStorage.ObjectT{Bucket: "backend-bucket", Path: "other/path/object"},
+			expectedErr:   true,
+		},
+		{
+			name:          "backend bucket not routed from request bucket",
+			requestBucket: "plain",
+			backend:       objectStorage.ObjectT{Bucket: "backend-bucket", Path: "path/object"},
+			expectedErr:   true,
+		},
+		{
+			name:          "not routed request bucket",
+			requestBucket: "other",
+			backend:       objectStorage.ObjectT{Bucket: "plain-bucket", Path: "path/object"},
+			expectedErr:   true,
+		},
+	}
+
+	r := getTestRouter()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, source, err := r.GetRequestObject(objectStorage.ObjectT{Bucket: test.requestBucket}, test.backend)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected routing error, got %+v", request)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to route object: %s", err.Error())
+			}
+
+			if source != "backend-source" {
+				t.Fatalf("source is '%s', expected 'backend-source'", source)
+			}
+			if request.Bucket != test.requestBucket || request.Path != test.expectedPath {
+				t.Fatalf("request object is %s/%s, expected %s/%s",
+					request.Bucket, request.Path, test.requestBucket, test.expectedPath)
+			}
+
+			// the request is routed back to the listed backend object
+			backend, _, err := r.GetBackendObject(request)
+			if err != nil {
+				t.Fatalf("unable to route request object: %s", err.Error())
+			}
+			if backend.Bucket != test.backend.Bucket || backend.Path != test.backend.Path {
+				t.Fatalf("request routed to %s/%s, expected %s/%s",
+					backend.Bucket, backend.Path, test.backend.Bucket, test.backend.Path)
+			}
+		})
+	}
+}
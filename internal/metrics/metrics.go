@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "bot"
+
+	LabelRoute         = "route"
+	LabelResult        = "result"
+	LabelBackendSource = "backend_source"
+	LabelFrontSource   = "front_source"
+
+	ResultAccepted = "accepted"
+	ResultRejected = "rejected"
+	ResultSuccess  = "success"
+	ResultFailure  = "failure"
+)
+
+var (
+	// API SERVICE
+
+	APIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "requests_total",
+		Help:      "Number of API requests accepted and rejected by route.",
+	}, []string{LabelRoute, LabelResult})
+
+	// OBJECT WORKER
+
+	ObjectPoolLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "pool_length",
+		Help:      "Number of object requests waiting in the pool.",
+	})
+
+	ObjectActiveRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "active_requests",
+		Help:      "Number of object requests handled in the current iteration.",
+	})
+
+	ObjectActiveThreads = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "active_threads",
+		Help:      "Number of child threads launched in the current iteration.",
+	})
+
+	ObjectTransferBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "transfer_bytes_total",
+		Help:      "Bytes read from the backend source in object transfers.",
+	}, []string{LabelBackendSource, LabelFrontSource, LabelResult})
+
+	ObjectServerCopyBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "server_copy_bytes_total",
+		Help:      "Bytes copied inside the backend in server-side object transfers, not read by the instance.",
+	}, []string{LabelBackendSource, LabelFrontSource, LabelResult})
+
+	ObjectTransferDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "transfer_duration_seconds",
+		Help:      "Duration of object transfers.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+	}, []string{LabelBackendSource, LabelFrontSource, LabelResult})
+
+	ObjectTransfersSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "transfers_skipped_total",
+		Help:      "Number of object transfers skipped by the route policy.",
+	}, []string{LabelBackendSource, LabelFrontSource})
+
+	ObjectVerificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "verification_failures_total",
+		Help:      "Number of object transfers with integrity mismatches after the copy.",
+	}, []string{LabelBackendSource, LabelFrontSource})
+
+	ObjectForwardedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "object_worker",
+		Name:      "forwarded_requests_total",
+		Help:      "Number of object requests forwarded to the hashring instance owning the object.",
+	}, []string{LabelResult})
+
+	// DATABASE WORKER
+
+	DatabasePoolLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "database_worker",
+		Name:      "pool_length",
+		Help:      "Number of database requests waiting in the pool.",
+	})
+
+	DatabaseActiveRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "database_worker",
+		Name:      "active_requests",
+		Help:      "Number of database requests handled in the current iteration.",
+	})
+
+	DatabaseActiveThreads = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "database_worker",
+		Name:      "active_threads",
+		Help:      "Number of child threads launched in the current iteration.",
+	})
+
+	DatabaseBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "database_worker",
+		Name:      "batch_size",
+		Help:      "Number of requests inserted in each database batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	DatabaseInsertDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "database_worker",
+		Name:      "insert_duration_seconds",
+		Help:      "Duration of database batch inserts.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{LabelResult})
+
+	// HASHRING WORKER
+
+	HashringMembers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "hashring_worker",
+		Name:      "members",
+		Help:      "Number of instances in the hashring.",
+	})
+
+	HashringRebalances = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "hashring_worker",
+		Name:      "rebalances_total",
+		Help:      "Number of hashring updates caused by instances added or removed.",
+	})
+)
+
+// GetResult returns the result label value for an operation error.
+func GetResult(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
@@ -1,30 +1,128 @@
 package pools
 
 import (
+	"encoding/json"
 	"fmt"
 	"maps"
 	"sync"
+	"time"
 )
 
 type DatabaseRequestPoolT struct {
 	mu       sync.Mutex
 	requests map[string]DatabaseRequestT
+	journal  *JournalT
+	// keyByTransfer keeps a request by transfer instead of one by object,
+	// to record every transfer of the objects
+	keyByTransfer bool
 }
 
 type DatabaseRequestT struct {
+	TransferId string `json:"transferId,omitempty"`
 	BucketName string `json:"bucket"`
 	ObjectPath string `json:"path"`
 	MD5        string `json:"md5"`
+
+	ChecksumAlgorithm string    `json:"checksumAlgorithm,omitempty"`
+	Checksum          string    `json:"checksum,omitempty"`
+	Size              int64     `json:"size,omitempty"`
+	ContentType       string    `json:"contentType,omitempty"`
+	Source            string    `json:"source,omitempty"`
+	TransferredAt     time.Time `json:"transferredAt,omitempty"`
+	Instance          string    `json:"instance,omitempty"`
+
+	Attempts  int       `json:"attempts,omitempty"`
+	NotBefore time.Time `json:"notBefore,omitempty"`
 }
 
-func NewDatabaseRequestPool() *DatabaseRequestPoolT {
+// NewDatabaseRequestPool returns a pool with a request by object, replacing the pending request
+// of an object with the newer ones, or a request by transfer when keyByTransfer is set.
+func NewDatabaseRequestPool(keyByTransfer bool) *DatabaseRequestPoolT {
 	return &DatabaseRequestPoolT{
-		requests: map[string]DatabaseRequestT{},
+		requests:      map[string]DatabaseRequestT{},
+		keyByTransfer: keyByTransfer,
 	}
 }
 
 // SERVER POOL FUNCTIONS
 
+// SetJournal replays the journal content into the pool and keeps it
+// to persist every following change in the pool.
+func (pool *DatabaseRequestPoolT) SetJournal(journal *JournalT) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	err = journal.Replay(func(entry JournalEntryT) (err error) {
+		switch entry.Operation {
+		case JournalOperationAdd:
+			request := DatabaseRequestT{}
+			if err = json.Unmarshal(entry.Data, &request); err != nil {
+				return err
+			}
+			pool.requests[entry.Key] = request
+		case JournalOperationRemove:
+			delete(pool.requests, entry.Key)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	pool.journal = journal
+
+	// the journals written with other pool keys, by an older version or with other
+	// write mode, are rewritten with the current keys
+	rekeyed := false
+	requests := map[string]DatabaseRequestT{}
+	for key, request := range pool.requests {
+		newKey := pool.getKey(request)
+		rekeyed = rekeyed || key != newKey
+
+		// the latest transfer is kept when several of them share the new key
+		if current, ok := requests[newKey]; !ok || request.TransferredAt.After(current.TransferredAt) {
+			requests[newKey] = request
+		}
+	}
+	pool.requests = requests
+
+	if rekeyed {
+		err = pool.compact()
+	}
+	return err
+}
+
+// Compact rewrites the pool journal with the current pool content only.
+func (pool *DatabaseRequestPoolT) Compact() (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	err = pool.compact()
+	return err
+}
+
+// compact rewrites the pool journal, the pool lock must be held.
+func (pool *DatabaseRequestPoolT) compact() (err error) {
+	if pool.journal == nil {
+		return err
+	}
+
+	entries := []JournalEntryT{}
+	for key, request := range pool.requests {
+		requestBytes, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       key,
+			Data:      requestBytes,
+		})
+	}
+
+	err = pool.journal.Compact(entries)
+	return err
+}
+
 func (pool *DatabaseRequestPoolT) GetPool() (result map[string]DatabaseRequestT) {
 	result = map[string]DatabaseRequestT{}
 
@@ -35,26 +133,110 @@ func (pool *DatabaseRequestPoolT) GetPool() (result map[string]DatabaseRequestT)
 	return result
 }
 
-func (pool *DatabaseRequestPoolT) AddRequest(request DatabaseRequestT) {
+func (pool *DatabaseRequestPoolT) AddRequest(request DatabaseRequestT) (err error) {
 	pool.mu.Lock()
-	pool.requests[request.ObjectPath] = request
-	pool.mu.Unlock()
+	defer pool.mu.Unlock()
+
+	err = pool.addRequest(request)
+	return err
 }
 
-func (pool *DatabaseRequestPoolT) RemoveRequest(key string) {
+// addRequest stores the request in the pool, the pool lock must be held.
+func (pool *DatabaseRequestPoolT) addRequest(request DatabaseRequestT) (err error) {
+	if pool.journal != nil {
+		requestBytes, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       pool.getKey(request),
+			Data:      requestBytes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	pool.requests[pool.getKey(request)] = request
+	return err
+}
+
+func (pool *DatabaseRequestPoolT) RemoveRequest(key string) (err error) {
 	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
 	delete(pool.requests, key)
-	pool.mu.Unlock()
+
+	if pool.journal != nil {
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationRemove,
+			Key:       key,
+		})
+	}
+
+	return err
 }
 
-func (pool *DatabaseRequestPoolT) RemoveRequests(requests []DatabaseRequestT) {
+func (pool *DatabaseRequestPoolT) RemoveRequests(requests []DatabaseRequestT) (err error) {
 	pool.mu.Lock()
-	for _, req := range pool.requests {
-		delete(pool.requests, req.ObjectPath)
+	defer pool.mu.Unlock()
+
+	for _, req := range requests {
+		// the newer requests for the object added while processing these ones are kept
+		key := pool.getKey(req)
+		if current, ok := pool.requests[key]; !ok || !current.isSameTransfer(req) {
+			continue
+		}
+
+		delete(pool.requests, key)
+
+		if pool.journal != nil {
+			err = pool.journal.Append(JournalEntryT{
+				Operation: JournalOperationRemove,
+				Key:       key,
+			})
+			if err != nil {
+				return err
+			}
+		}
 	}
-	pool.mu.Unlock()
+
+	return err
+}
+
+// ReplaceRequest stores the request only when the one in the pool for its object is
+// of the same transfer, so a processed request is not stored over a newer one.
+// It returns if the request was stored.
+func (pool *DatabaseRequestPoolT) ReplaceRequest(request DatabaseRequestT) (replaced bool, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if current, ok := pool.requests[pool.getKey(request)]; !ok || !current.isSameTransfer(request) {
+		return replaced, err
+	}
+
+	err = pool.addRequest(request)
+	return err == nil, err
+}
+
+// getKey returns the pool key of the request, its object or its transfer when the pool
+// keeps a request by transfer (the requests without transfer id are kept by object).
+func (pool *DatabaseRequestPoolT) getKey(request DatabaseRequestT) string {
+	if pool.keyByTransfer && request.TransferId != "" {
+		return request.TransferId
+	}
+
+	// the bucket names can not have slashes
+	return request.BucketName + "/" + request.ObjectPath
 }
 
 func (d *DatabaseRequestT) String() string {
 	return fmt.Sprintf("{bucket: '%s', object: '%s'}", d.BucketName, d.ObjectPath)
 }
+
+// isSameTransfer returns if both requests record the same object transfer.
+func (d *DatabaseRequestT) isSameTransfer(other DatabaseRequestT) bool {
+	return d.TransferId == other.TransferId && d.TransferredAt.Equal(other.TransferredAt)
+}
@@ -0,0 +1,137 @@
+package pools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatabaseRequestPoolAddRequest(t *testing.T) {
+	transferredAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		keyByTransfer bool
+		requests      []DatabaseRequestT
+
+		expectedKeys []string
+	}{
+		{
+			name: "newer transfer replaces the object request",
+			requests: []DatabaseRequestT{
+				{TransferId: "a", BucketName: "bucket", ObjectPath: "object", TransferredAt: transferredAt},
+				{TransferId: "b", BucketName: "bucket", ObjectPath: "object", TransferredAt: transferredAt.Add(time.Second)},
+			},
+			expectedKeys: []string{"bucket/object"},
+		},
+		{
+			name: "same path in other bucket",
+			requests: []DatabaseRequestT{
+				{TransferId: "a", BucketName: "bucket-a", ObjectPath: "object"},
+				{TransferId: "b", BucketName: "bucket-b", ObjectPath: "object"},
+			},
+			expectedKeys: []string{"bucket-a/object", "bucket-b/object"},
+		},
+		{
+			name:          "request by transfer keeps the history",
+			keyByTransfer: true,
+			requests: []DatabaseRequestT{
+				{TransferId: "a", BucketName: "bucket", ObjectPath: "object"},
+				{TransferId: "b", BucketName: "bucket", ObjectPath: "object"},
+			},
+			expectedKeys: []string{"a", "b"},
+		},
+		{
+			name:          "request by transfer without transfer id",
+			keyByTransfer: true,
+			requests: []DatabaseRequestT{
+				{BucketName: "bucket", ObjectPath: "object"},
+			},
+			expectedKeys: []string{"bucket/object"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := NewDatabaseRequestPool(test.keyByTransfer)
+			for _, request := range test.requests {
+				if err := pool.AddRequest(request); err != nil {
+					t.Fatalf("unable to add request: %s", err.Error())
+				}
+			}
+
+			requests := pool.GetPool()
+			if len(requests) != len(test.expectedKeys) {
+				t.Fatalf("pool has %d requests, expected %d: %v", len(requests), len(test.expectedKeys), requests)
+			}
+			for _, key := range test.expectedKeys {
+				if _, ok := requests[key]; !ok {
+					t.Fatalf("request '%s' not in pool: %v", key, requests)
+				}
+			}
+
+			// the last request of every key is the one kept
+			last := test.requests[len(test.requests)-1]
+			if request := requests[pool.getKey(last)]; request.TransferId != last.TransferId {
+				t.Fatalf("pool kept transfer '%s', expected '%s'", request.TransferId, last.TransferId)
+			}
+		})
+	}
+}
+
+func TestDatabaseRequestPoolProcessedRequests(t *testing.T) {
+	processed := DatabaseRequestT{TransferId: "a", BucketName: "bucket", ObjectPath: "object"}
+
+	tests := []struct {
+		name string
+		// newer adds a newer request of the object while the first one is processed
+		newer bool
+
+		expectedReplaced bool
+		expectedLength   int
+	}{
+		{
+			name:             "without newer request",
+			expectedReplaced: true,
+			expectedLength:   0,
+		},
+		{
+			name:             "with newer request",
+			newer:            true,
+			expectedReplaced: false,
+			expectedLength:   1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := NewDatabaseRequestPool(false)
+			if err := pool.AddRequest(processed); err != nil {
+				t.Fatalf("unable to add request: %s", err.Error())
+			}
+			if test.newer {
+				newer := processed
+				newer.TransferId = "b"
+				if err := pool.AddRequest(newer); err != nil {
+					t.Fatalf("unable to add newer request: %s", err.Error())
+				}
+			}
+
+			retried := processed
+			retried.Attempts++
+			replaced, err := pool.ReplaceRequest(retried)
+			if err != nil {
+				t.Fatalf("unable to replace request: %s", err.Error())
+			}
+			if replaced != test.expectedReplaced {
+				t.Fatalf("replaced is %t, expected %t", replaced, test.expectedReplaced)
+			}
+
+			if err = pool.RemoveRequests([]DatabaseRequestT{processed}); err != nil {
+				t.Fatalf("unable to remove requests: %s", err.Error())
+			}
+			if length := len(pool.GetPool()); length != test.expectedLength {
+				t.Fatalf("pool has %d requests, expected %d", length, test.expectedLength)
+			}
+		})
+	}
+}
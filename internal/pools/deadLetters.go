@@ -0,0 +1,154 @@
+package pools
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+	"time"
+)
+
+const (
+	DeadLetterKindObject   = "object"
+	DeadLetterKindDatabase = "database"
+)
+
+// DeadLetterPoolT stores the requests that consumed all their attempts
+// until they are retried or purged through the API.
+type DeadLetterPoolT struct {
+	mu      sync.Mutex
+	letters map[string]DeadLetterT
+	journal *JournalT
+}
+
+type DeadLetterT struct {
+	Id              string            `json:"id"`
+	Kind            string            `json:"kind"`
+	Error           string            `json:"error"`
+	Attempts        int               `json:"attempts"`
+	FailedAt        time.Time         `json:"failedAt"`
+	ObjectRequest   *ObjectRequestT   `json:"objectRequest,omitempty"`
+	DatabaseRequest *DatabaseRequestT `json:"databaseRequest,omitempty"`
+}
+
+func NewDeadLetterPool() *DeadLetterPoolT {
+	return &DeadLetterPoolT{
+		letters: map[string]DeadLetterT{},
+	}
+}
+
+// DEAD LETTER POOL FUNCTIONS
+
+// SetJournal replays the journal content into the pool and keeps it
+// to persist every following change in the pool.
+func (pool *DeadLetterPoolT) SetJournal(journal *JournalT) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	err = journal.Replay(func(entry JournalEntryT) (err error) {
+		switch entry.Operation {
+		case JournalOperationAdd:
+			letter := DeadLetterT{}
+			if err = json.Unmarshal(entry.Data, &letter); err != nil {
+				return err
+			}
+			pool.letters[entry.Key] = letter
+		case JournalOperationRemove:
+			delete(pool.letters, entry.Key)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	pool.journal = journal
+	return err
+}
+
+// Compact rewrites the pool journal with the current pool content only.
+func (pool *DeadLetterPoolT) Compact() (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.journal == nil {
+		return err
+	}
+
+	entries := []JournalEntryT{}
+	for key, letter := range pool.letters {
+		letterBytes, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       key,
+			Data:      letterBytes,
+		})
+	}
+
+	err = pool.journal.Compact(entries)
+	return err
+}
+
+func (pool *DeadLetterPoolT) GetPool() (result map[string]DeadLetterT) {
+	result = map[string]DeadLetterT{}
+
+	pool.mu.Lock()
+	maps.Copy(result, pool.letters)
+	pool.mu.Unlock()
+
+	return result
+}
+
+func (pool *DeadLetterPoolT) GetLetter(id string) (letter DeadLetterT, ok bool) {
+	pool.mu.Lock()
+	letter, ok = pool.letters[id]
+	pool.mu.Unlock()
+
+	return letter, ok
+}
+
+func (pool *DeadLetterPoolT) AddLetter(letter DeadLetterT) (err error) {
+	if letter.Id == "" {
+		letter.Id = NewRequestId()
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.journal != nil {
+		letterBytes, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       letter.Id,
+			Data:      letterBytes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	pool.letters[letter.Id] = letter
+	return err
+}
+
+func (pool *DeadLetterPoolT) RemoveLetter(id string) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	delete(pool.letters, id)
+
+	if pool.journal != nil {
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationRemove,
+			Key:       id,
+		})
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+package pools
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bot/internal/managers/objectStorage"
+)
+
+func TestDeadLetterPoolJournal(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "deadLetters.journal")
+	journal, err := NewJournal(journalPath, false)
+	if err != nil {
+		t.Fatalf("unable to open journal: %s", err.Error())
+	}
+
+	pool := NewDeadLetterPool()
+	if err = pool.SetJournal(journal); err != nil {
+		t.Fatalf("unable to set journal: %s", err.Error())
+	}
+
+	for _, id := range []string{"kept", "removed"} {
+		err = pool.AddLetter(DeadLetterT{
+			Id:       id,
+			Kind:     DeadLetterKindObject,
+			Attempts: 5,
+			ObjectRequest: &ObjectRequestT{
+				Id:     id,
+				Object: objectStorage.ObjectT{Bucket: "bucket", Path: id},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unable to add dead letter: %s", err.Error())
+		}
+	}
+	if err = pool.RemoveLetter("removed"); err != nil {
+		t.Fatalf("unable to remove dead letter: %s", err.Error())
+	}
+	journal.Close()
+
+	journal, err = NewJournal(journalPath, false)
+	if err != nil {
+		t.Fatalf("unable to open journal: %s", err.Error())
+	}
+	defer journal.Close()
+
+	replayed := NewDeadLetterPool()
+	if err = replayed.SetJournal(journal); err != nil {
+		t.Fatalf("unable to replay journal: %s", err.Error())
+	}
+
+	letters := replayed.GetPool()
+	letter, ok := letters["kept"]
+	if len(letters) != 1 || !ok {
+		t.Fatalf("replayed dead letters are %v, expected only 'kept'", letters)
+	}
+	if letter.ObjectRequest == nil || letter.ObjectRequest.Object.Path != "kept" || letter.Attempts != 5 {
+		t.Fatalf("replayed dead letter is %+v", letter)
+	}
+}
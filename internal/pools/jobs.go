@@ -0,0 +1,226 @@
+package pools
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	JobStateRunning   = "running"
+	JobStatePaused    = "paused"
+	JobStateCompleted = "completed"
+	JobStateFailed    = "failed"
+)
+
+// JobPoolT stores the bulk migration jobs with their progress. The checkpoint
+// of every job is the last listed object path, so a job can continue
+// the listing from there after a restart.
+type JobPoolT struct {
+	mu      sync.Mutex
+	jobs    map[string]JobT
+	journal *JournalT
+}
+
+type JobT struct {
+	Id            string      `json:"id"`
+	Source        string      `json:"source"`
+	Bucket        string      `json:"bucket"`
+	Prefix        string      `json:"prefix"`
+	RequestBucket string      `json:"requestBucket,omitempty"`
+	Metadata      http.Header `json:"metadata,omitempty"`
+
+	State      string `json:"state"`
+	Error      string `json:"error,omitempty"`
+	Checkpoint string `json:"checkpoint"`
+	// ListMarker is the backend marker of the next page, for the sources listing from markers
+	ListMarker string    `json:"listMarker,omitempty"`
+	Listed     int64     `json:"listed"`
+	Enqueued   int64     `json:"enqueued"`
+	Rejected   int64     `json:"rejected"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+func NewJobPool() *JobPoolT {
+	return &JobPoolT{
+		jobs: map[string]JobT{},
+	}
+}
+
+// JOB POOL FUNCTIONS
+
+// SetJournal replays the journal content into the pool and keeps it
+// to persist every following change in the pool.
+func (pool *JobPoolT) SetJournal(journal *JournalT) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	err = journal.Replay(func(entry JournalEntryT) (err error) {
+		switch entry.Operation {
+		case JournalOperationAdd:
+			job := JobT{}
+			if err = json.Unmarshal(entry.Data, &job); err != nil {
+				return err
+			}
+			pool.jobs[entry.Key] = job
+		case JournalOperationRemove:
+			delete(pool.jobs, entry.Key)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	pool.journal = journal
+	return err
+}
+
+// Compact rewrites the pool journal with the current pool content only.
+func (pool *JobPoolT) Compact() (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.journal == nil {
+		return err
+	}
+
+	entries := []JournalEntryT{}
+	for key, job := range pool.jobs {
+		jobBytes, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       key,
+			Data:      jobBytes,
+		})
+	}
+
+	err = pool.journal.Compact(entries)
+	return err
+}
+
+func (pool *JobPoolT) GetPool() (result map[string]JobT) {
+	result = map[string]JobT{}
+
+	pool.mu.Lock()
+	maps.Copy(result, pool.jobs)
+	pool.mu.Unlock()
+
+	return result
+}
+
+func (pool *JobPoolT) GetJob(id string) (job JobT, ok bool) {
+	pool.mu.Lock()
+	job, ok = pool.jobs[id]
+	pool.mu.Unlock()
+
+	return job, ok
+}
+
+func (pool *JobPoolT) AddJob(job JobT) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	err = pool.setJob(job)
+	return err
+}
+
+// SetJobState changes the state of a stored job, only if its current state is one of the
+// given ones (any when there is none), and returns the updated job.
+func (pool *JobPoolT) SetJobState(id string, state string, reason string, fromStates ...string) (job JobT, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	job, ok := pool.jobs[id]
+	if !ok {
+		return job, fmt.Errorf("job '%s' not found", id)
+	}
+
+	if len(fromStates) > 0 {
+		allowed := false
+		for _, fromState := range fromStates {
+			allowed = allowed || job.State == fromState
+		}
+
+		if !allowed {
+			return job, fmt.Errorf("job '%s' in '%s' state can not move to '%s' state", id, job.State, state)
+		}
+	}
+
+	job.State = state
+	job.Error = reason
+	job.UpdatedAt = time.Now()
+	err = pool.setJob(job)
+
+	return job, err
+}
+
+// AddJobProgress moves the checkpoint of a stored job and adds the counters
+// of the last processed listing page, keeping its current state.
+func (pool *JobPoolT) AddJobProgress(id string, checkpoint string, listMarker string, listed int64, enqueued int64, rejected int64) (job JobT, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	job, ok := pool.jobs[id]
+	if !ok {
+		return job, fmt.Errorf("job '%s' not found", id)
+	}
+
+	job.Checkpoint = checkpoint
+	job.ListMarker = listMarker
+	job.Listed += listed
+	job.Enqueued += enqueued
+	job.Rejected += rejected
+	job.UpdatedAt = time.Now()
+	err = pool.setJob(job)
+
+	return job, err
+}
+
+func (pool *JobPoolT) RemoveJob(id string) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	delete(pool.jobs, id)
+
+	if pool.journal != nil {
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationRemove,
+			Key:       id,
+		})
+	}
+
+	return err
+}
+
+func (pool *JobPoolT) setJob(job JobT) (err error) {
+	if pool.journal != nil {
+		jobBytes, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       job.Id,
+			Data:      jobBytes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	pool.jobs[job.Id] = job
+	return err
+}
+
+func (j *JobT) String() string {
+	return fmt.Sprintf("{id: '%s', source: '%s', bucket: '%s', prefix: '%s'}", j.Id, j.Source, j.Bucket, j.Prefix)
+}
@@ -0,0 +1,183 @@
+package pools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	JournalOperationAdd    = "add"
+	JournalOperationRemove = "remove"
+)
+
+// JournalT is an append-only log of pool operations stored in a single file.
+// Replaying it from the beginning rebuilds the pool content it belongs to.
+type JournalT struct {
+	mu         sync.Mutex
+	filepath   string
+	syncWrites bool
+	file       *os.File
+}
+
+type JournalEntryT struct {
+	Operation string          `json:"op"`
+	Key       string          `json:"key"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+func NewJournal(filepath string, syncWrites bool) (j *JournalT, err error) {
+	j = &JournalT{
+		filepath:   filepath,
+		syncWrites: syncWrites,
+	}
+
+	j.file, err = os.OpenFile(j.filepath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return j, err
+	}
+
+	return j, err
+}
+
+// JOURNAL FUNCTIONS
+
+func (j *JournalT) Append(entry JournalEntryT) (err error) {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	entryBytes = append(entryBytes, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err = j.file.Write(entryBytes)
+	if err != nil {
+		return err
+	}
+
+	if j.syncWrites {
+		err = j.file.Sync()
+	}
+
+	return err
+}
+
+// Replay reads the journal file from the beginning and calls apply for every entry.
+// A truncated last line (crash in the middle of a write) is ignored and removed from the file,
+// so the next appended entry does not follow it in the same line.
+func (j *JournalT) Replay(apply func(entry JournalEntryT) error) (err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.Open(j.filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// offset is the end of the last complete entry in the file
+	offset := int64(0)
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		complete := readErr == nil
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			entry := JournalEntryT{}
+			if err = json.Unmarshal(line, &entry); err != nil {
+				// only the last line can be partially written
+				if complete {
+					return fmt.Errorf("corrupted entry in journal '%s': %s", j.filepath, err.Error())
+				}
+				return j.file.Truncate(offset)
+			}
+
+			if err = apply(entry); err != nil {
+				return err
+			}
+
+			// the whole entry was written but its line end was not
+			if !complete {
+				_, err = j.file.Write([]byte{'\n'})
+				return err
+			}
+		}
+
+		if !complete {
+			return err
+		}
+		offset += int64(len(line))
+	}
+}
+
+// Compact replaces the journal content with the given entries. The new content is
+// written in a temporary file and renamed over the old one, so a crash in the middle
+// keeps the previous journal intact.
+func (j *JournalT) Compact(entries []JournalEntryT) (err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(j.filepath), filepath.Base(j.filepath)+".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	writer := bufio.NewWriter(tmpFile)
+	for _, entry := range entries {
+		entryBytes, err := json.Marshal(entry)
+		if err != nil {
+			tmpFile.Close()
+			return err
+		}
+		// a short write must not replace the journal with a truncated one
+		if _, err = writer.Write(entryBytes); err == nil {
+			err = writer.WriteByte('\n')
+		}
+		if err != nil {
+			tmpFile.Close()
+			return err
+		}
+	}
+
+	if err = writer.Flush(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpFile.Name(), j.filepath); err != nil {
+		return err
+	}
+
+	j.file.Close()
+	j.file, err = os.OpenFile(j.filepath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+
+	return err
+}
+
+func (j *JournalT) Close() (err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err = j.file.Close()
+	return err
+}
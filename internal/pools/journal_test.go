@@ -0,0 +1,144 @@
+package pools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// replayKeys replays the journal and returns the keys of its entries in order.
+func replayKeys(t *testing.T, journal *JournalT) (keys []string, err error) {
+	t.Helper()
+
+	err = journal.Replay(func(entry JournalEntryT) error {
+		keys = append(keys, entry.Operation+":"+entry.Key)
+		return nil
+	})
+	return keys, err
+}
+
+func TestJournalReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// expected are the entries replayed before and after appending a new entry
+		expected       []string
+		expectedAppend []string
+		expectedErr    bool
+	}{
+		{
+			name:           "empty journal",
+			content:        "",
+			expected:       nil,
+			expectedAppend: []string{"add:new"},
+		},
+		{
+			name:           "complete entries",
+			content:        `{"op":"add","key":"a"}` + "\n" + `{"op":"remove","key":"a"}` + "\n",
+			expected:       []string{"add:a", "remove:a"},
+			expectedAppend: []string{"add:a", "remove:a", "add:new"},
+		},
+		{
+			name:           "truncated last line",
+			content:        `{"op":"add","key":"a"}` + "\n" + `{"op":"add","ke`,
+			expected:       []string{"add:a"},
+			expectedAppend: []string{"add:a", "add:new"},
+		},
+		{
+			name:           "last entry without line end",
+			content:        `{"op":"add","key":"a"}` + "\n" + `{"op":"add","key":"b"}`,
+			expected:       []string{"add:a", "add:b"},
+			expectedAppend: []string{"add:a", "add:b", "add:new"},
+		},
+		{
+			name:        "corrupted middle line",
+			content:     `{"op":"add","key":"a"}` + "\n" + `{"op":"add","ke` + "\n" + `{"op":"add","key":"b"}` + "\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			journalPath := filepath.Join(t.TempDir(), "test.journal")
+			if err := os.WriteFile(journalPath, []byte(test.content), 0o640); err != nil {
+				t.Fatalf("unable to write journal: %s", err.Error())
+			}
+
+			journal, err := NewJournal(journalPath, false)
+			if err != nil {
+				t.Fatalf("unable to open journal: %s", err.Error())
+			}
+
+			keys, err := replayKeys(t, journal)
+			if test.expectedErr {
+				if err == nil {
+					t.Fatalf("expected replay error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to replay journal: %s", err.Error())
+			}
+			if !slices.Equal(keys, test.expected) {
+				t.Fatalf("replayed %v, expected %v", keys, test.expected)
+			}
+
+			if err = journal.Append(JournalEntryT{Operation: JournalOperationAdd, Key: "new"}); err != nil {
+				t.Fatalf("unable to append entry: %s", err.Error())
+			}
+			journal.Close()
+
+			// the journal is replayed again as in the next start
+			journal, err = NewJournal(journalPath, false)
+			if err != nil {
+				t.Fatalf("unable to open journal: %s", err.Error())
+			}
+			defer journal.Close()
+
+			keys, err = replayKeys(t, journal)
+			if err != nil {
+				t.Fatalf("unable to replay journal after append: %s", err.Error())
+			}
+			if !slices.Equal(keys, test.expectedAppend) {
+				t.Fatalf("replayed %v after append, expected %v", keys, test.expectedAppend)
+			}
+		})
+	}
+}
+
+func TestJournalCompact(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "test.journal")
+	journal, err := NewJournal(journalPath, true)
+	if err != nil {
+		t.Fatalf("unable to open journal: %s", err.Error())
+	}
+	defer journal.Close()
+
+	for _, entry := range []JournalEntryT{
+		{Operation: JournalOperationAdd, Key: "a"},
+		{Operation: JournalOperationAdd, Key: "b"},
+		{Operation: JournalOperationRemove, Key: "a"},
+	} {
+		if err = journal.Append(entry); err != nil {
+			t.Fatalf("unable to append entry: %s", err.Error())
+		}
+	}
+
+	if err = journal.Compact([]JournalEntryT{{Operation: JournalOperationAdd, Key: "b"}}); err != nil {
+		t.Fatalf("unable to compact journal: %s", err.Error())
+	}
+
+	// the entries appended after the compaction go to the new file
+	if err = journal.Append(JournalEntryT{Operation: JournalOperationAdd, Key: "c"}); err != nil {
+		t.Fatalf("unable to append entry: %s", err.Error())
+	}
+
+	keys, err := replayKeys(t, journal)
+	if err != nil {
+		t.Fatalf("unable to replay journal: %s", err.Error())
+	}
+	if expected := []string{"add:b", "add:c"}; !slices.Equal(keys, expected) {
+		t.Fatalf("replayed %v, expected %v", keys, expected)
+	}
+}
@@ -1,9 +1,11 @@
 package pools
 
 import (
+	"encoding/json"
 	"fmt"
 	"maps"
 	"sync"
+	"time"
 
 	"bot/internal/managers/objectStorage"
 )
@@ -11,10 +13,17 @@ import (
 type ObjectRequestPoolT struct {
 	mu       sync.Mutex
 	requests map[string]ObjectRequestT
+	journal  *JournalT
 }
 
 type ObjectRequestT struct {
-	Object objectStorage.ObjectT
+	Id        string
+	Object    objectStorage.ObjectT
+	Attempts  int
+	NotBefore time.Time
+	// Forwarded is set in the requests forwarded by other instance of the hashring,
+	// to process them locally instead of forwarding them again
+	Forwarded bool
 }
 
 func NewObjectRequestPool() *ObjectRequestPoolT {
@@ -25,6 +34,79 @@ func NewObjectRequestPool() *ObjectRequestPoolT {
 
 // REQUEST POOL FUNCTIONS
 
+// SetJournal replays the journal content into the pool and keeps it
+// to persist every following change in the pool.
+func (pool *ObjectRequestPoolT) SetJournal(journal *JournalT) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	err = journal.Replay(func(entry JournalEntryT) (err error) {
+		switch entry.Operation {
+		case JournalOperationAdd:
+			request := ObjectRequestT{}
+			if err = json.Unmarshal(entry.Data, &request); err != nil {
+				return err
+			}
+			pool.requests[entry.Key] = request
+		case JournalOperationRemove:
+			delete(pool.requests, entry.Key)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	pool.journal = journal
+
+	// the journals written by older versions, with the requests by object path only,
+	// are rewritten with the current keys
+	rekeyed := false
+	requests := map[string]ObjectRequestT{}
+	for key, request := range pool.requests {
+		newKey := getObjectRequestKey(request.Object)
+		rekeyed = rekeyed || key != newKey
+		requests[newKey] = request
+	}
+	pool.requests = requests
+
+	if rekeyed {
+		err = pool.compact()
+	}
+	return err
+}
+
+// Compact rewrites the pool journal with the current pool content only.
+func (pool *ObjectRequestPoolT) Compact() (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	err = pool.compact()
+	return err
+}
+
+// compact rewrites the pool journal, the pool lock must be held.
+func (pool *ObjectRequestPoolT) compact() (err error) {
+	if pool.journal == nil {
+		return err
+	}
+
+	entries := []JournalEntryT{}
+	for key, request := range pool.requests {
+		requestBytes, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       key,
+			Data:      requestBytes,
+		})
+	}
+
+	err = pool.journal.Compact(entries)
+	return err
+}
+
 func (pool *ObjectRequestPoolT) GetPool() (result map[string]ObjectRequestT) {
 	result = map[string]ObjectRequestT{}
 
@@ -35,24 +117,155 @@ func (pool *ObjectRequestPoolT) GetPool() (result map[string]ObjectRequestT) {
 	return result
 }
 
-func (pool *ObjectRequestPoolT) AddRequest(transfer ObjectRequestT) {
+func (pool *ObjectRequestPoolT) GetRequest(key string) (request ObjectRequestT, ok bool) {
 	pool.mu.Lock()
-	pool.requests[transfer.Object.Path] = transfer
+	request, ok = pool.requests[key]
 	pool.mu.Unlock()
+
+	return request, ok
 }
 
-func (pool *ObjectRequestPoolT) RemoveRequest(key string) {
+func (pool *ObjectRequestPoolT) AddRequest(transfer ObjectRequestT) (err error) {
 	pool.mu.Lock()
-	delete(pool.requests, key)
+	defer pool.mu.Unlock()
+
+	_, _, err = pool.addRequest(transfer)
+	return err
+}
+
+// AddTrackedRequest adds the request in the pool with a new id and registers its status.
+// A request for the same object still waiting in the pool is replaced and marked as skipped,
+// while one being processed keeps running and is not removed from the pool by the new one.
+func (pool *ObjectRequestPoolT) AddTrackedRequest(statusPool *TransferStatusPoolT, request ObjectRequestT) (ObjectRequestT, error) {
+	request.Id = NewRequestId()
+	statusPool.AddStatus(request.Id, request.Object)
+
+	pool.mu.Lock()
+	previous, replaced, err := pool.addRequest(request)
 	pool.mu.Unlock()
+	if err != nil {
+		statusPool.SetState(request.Id, TransferStateFailed, err.Error())
+		return request, err
+	}
+
+	if replaced {
+		if status, ok := statusPool.GetStatus(previous.Id); ok &&
+			(status.State == TransferStateQueued || status.State == TransferStateRetrying) {
+			statusPool.SetState(previous.Id, TransferStateSkipped,
+				fmt.Sprintf("superseded by transfer request '%s'", request.Id))
+		}
+	}
+
+	return request, err
 }
 
-func (pool *ObjectRequestPoolT) RemoveRequests(requests []ObjectRequestT) {
+// ReplaceRequest stores the request only when the one in the pool for its object has the same id,
+// so a processed request is not stored over a newer one. It returns if the request was stored.
+func (pool *ObjectRequestPoolT) ReplaceRequest(transfer ObjectRequestT) (replaced bool, err error) {
 	pool.mu.Lock()
-	for _, req := range pool.requests {
-		delete(pool.requests, req.Object.Path)
+	defer pool.mu.Unlock()
+
+	if current, ok := pool.requests[getObjectRequestKey(transfer.Object)]; !ok || current.Id != transfer.Id {
+		return replaced, err
 	}
-	pool.mu.Unlock()
+
+	_, replaced, err = pool.addRequest(transfer)
+	return replaced, err
+}
+
+// addRequest stores the request, returning the one it replaces in the pool.
+// The pool lock must be held.
+func (pool *ObjectRequestPoolT) addRequest(transfer ObjectRequestT) (previous ObjectRequestT, replaced bool, err error) {
+	key := getObjectRequestKey(transfer.Object)
+
+	if pool.journal != nil {
+		transferBytes, err := json.Marshal(transfer)
+		if err != nil {
+			return previous, replaced, err
+		}
+
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationAdd,
+			Key:       key,
+			Data:      transferBytes,
+		})
+		if err != nil {
+			return previous, replaced, err
+		}
+	}
+
+	previous, replaced = pool.requests[key]
+	pool.requests[key] = transfer
+	return previous, replaced, err
+}
+
+func (pool *ObjectRequestPoolT) RemoveRequest(key string) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	delete(pool.requests, key)
+
+	if pool.journal != nil {
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationRemove,
+			Key:       key,
+		})
+	}
+
+	return err
+}
+
+func (pool *ObjectRequestPoolT) RemoveRequests(requests []ObjectRequestT) (err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for _, req := range requests {
+		key := getObjectRequestKey(req.Object)
+		delete(pool.requests, key)
+
+		if pool.journal != nil {
+			err = pool.journal.Append(JournalEntryT{
+				Operation: JournalOperationRemove,
+				Key:       key,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return err
+}
+
+// RemoveProcessedRequest removes the request from the pool only when the one in the pool
+// for its object has the same id, keeping the newer requests added while it was processed.
+func (pool *ObjectRequestPoolT) RemoveProcessedRequest(request ObjectRequestT) (removed bool, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	key := getObjectRequestKey(request.Object)
+	if current, ok := pool.requests[key]; !ok || current.Id != request.Id {
+		return removed, err
+	}
+
+	delete(pool.requests, key)
+	removed = true
+
+	if pool.journal != nil {
+		err = pool.journal.Append(JournalEntryT{
+			Operation: JournalOperationRemove,
+			Key:       key,
+		})
+	}
+
+	return removed, err
+}
+
+// getObjectRequestKey returns the pool key of the requests of the object,
+// with the bucket as the same path can be requested in several buckets.
+func getObjectRequestKey(object objectStorage.ObjectT) string {
+	// the bucket names can not have slashes
+	return object.Bucket + "/" + object.Path
 }
 
 func (or *ObjectRequestT) String() string {
@@ -0,0 +1,205 @@
+package pools
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bot/internal/managers/objectStorage"
+)
+
+func TestObjectRequestPoolAddTrackedRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		// first and second are the objects requested in order
+		first  objectStorage.ObjectT
+		second objectStorage.ObjectT
+		// firstInProcess sets the first request as being processed when the second one is added
+		firstInProcess bool
+
+		expectedPoolLength int
+		expectedFirstState string
+	}{
+		{
+			name:               "same object replaces the queued request",
+			first:              objectStorage.ObjectT{Bucket: "bucket", Path: "path/object"},
+			second:             objectStorage.ObjectT{Bucket: "bucket", Path: "path/object"},
+			expectedPoolLength: 1,
+			expectedFirstState: TransferStateSkipped,
+		},
+		{
+			name:               "same object keeps the request in process",
+			first:              objectStorage.ObjectT{Bucket: "bucket", Path: "path/object"},
+			second:             objectStorage.ObjectT{Bucket: "bucket", Path: "path/object"},
+			firstInProcess:     true,
+			expectedPoolLength: 1,
+			expectedFirstState: TransferStateCopying,
+		},
+		{
+			name:               "same path in other bucket",
+			first:              objectStorage.ObjectT{Bucket: "bucket-a", Path: "path/object"},
+			second:             objectStorage.ObjectT{Bucket: "bucket-b", Path: "path/object"},
+			expectedPoolLength: 2,
+			expectedFirstState: TransferStateQueued,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := NewObjectRequestPool()
+			statusPool := NewTransferStatusPool()
+
+			first, err := pool.AddTrackedRequest(statusPool, ObjectRequestT{Object: test.first})
+			if err != nil {
+				t.Fatalf("unable to add first request: %s", err.Error())
+			}
+			if test.firstInProcess {
+				statusPool.SetState(first.Id, TransferStateCopying, "")
+			}
+
+			second, err := pool.AddTrackedRequest(statusPool, ObjectRequestT{Object: test.second})
+			if err != nil {
+				t.Fatalf("unable to add second request: %s", err.Error())
+			}
+
+			if first.Id == second.Id {
+				t.Fatalf("requests share the id '%s'", first.Id)
+			}
+
+			if length := len(pool.GetPool()); length != test.expectedPoolLength {
+				t.Fatalf("pool length is %d, expected %d", length, test.expectedPoolLength)
+			}
+
+			status, ok := statusPool.GetStatus(first.Id)
+			if !ok {
+				t.Fatalf("first request status not found")
+			}
+			if status.State != test.expectedFirstState {
+				t.Fatalf("first request state is '%s', expected '%s'", status.State, test.expectedFirstState)
+			}
+		})
+	}
+}
+
+func TestObjectRequestPoolProcessedRequest(t *testing.T) {
+	object := objectStorage.ObjectT{Bucket: "bucket", Path: "path/object"}
+
+	tests := []struct {
+		name string
+		// newer adds a newer request for the object while the first one is processed
+		newer bool
+
+		expectedRemoved  bool
+		expectedReplaced bool
+	}{
+		{
+			name:             "without newer request",
+			expectedRemoved:  true,
+			expectedReplaced: true,
+		},
+		{
+			name:             "with newer request",
+			newer:            true,
+			expectedRemoved:  false,
+			expectedReplaced: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := NewObjectRequestPool()
+			statusPool := NewTransferStatusPool()
+
+			processed, err := pool.AddTrackedRequest(statusPool, ObjectRequestT{Object: object})
+			if err != nil {
+				t.Fatalf("unable to add request: %s", err.Error())
+			}
+			newer := processed
+			if test.newer {
+				newer, err = pool.AddTrackedRequest(statusPool, ObjectRequestT{Object: object})
+				if err != nil {
+					t.Fatalf("unable to add newer request: %s", err.Error())
+				}
+			}
+
+			processed.Attempts++
+			replaced, err := pool.ReplaceRequest(processed)
+			if err != nil {
+				t.Fatalf("unable to replace request: %s", err.Error())
+			}
+			if replaced != test.expectedReplaced {
+				t.Fatalf("replaced is %t, expected %t", replaced, test.expectedReplaced)
+			}
+
+			removed, err := pool.RemoveProcessedRequest(processed)
+			if err != nil {
+				t.Fatalf("unable to remove request: %s", err.Error())
+			}
+			if removed != test.expectedRemoved {
+				t.Fatalf("removed is %t, expected %t", removed, test.expectedRemoved)
+			}
+
+			// the newer request is kept unchanged in the pool
+			if test.newer {
+				request, ok := pool.GetRequest(getObjectRequestKey(object))
+				if !ok || request.Id != newer.Id || request.Attempts != 0 {
+					t.Fatalf("newer request not kept in pool: %+v", request)
+				}
+			}
+		})
+	}
+}
+
+func TestObjectRequestPoolJournal(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "objectRequests.journal")
+
+	// an older journal with the requests by object path only
+	journal, err := NewJournal(journalPath, false)
+	if err != nil {
+		t.Fatalf("unable to open journal: %s", err.Error())
+	}
+	err = journal.Append(JournalEntryT{
+		Operation: JournalOperationAdd,
+		Key:       "path/old",
+		Data:      []byte(`{"Id":"old","Object":{"bucket":"bucket","path":"path/old"}}`),
+	})
+	if err != nil {
+		t.Fatalf("unable to append entry: %s", err.Error())
+	}
+
+	pool := NewObjectRequestPool()
+	if err = pool.SetJournal(journal); err != nil {
+		t.Fatalf("unable to set journal: %s", err.Error())
+	}
+
+	for _, bucket := range []string{"bucket-a", "bucket-b"} {
+		err = pool.AddRequest(ObjectRequestT{Id: bucket, Object: objectStorage.ObjectT{Bucket: bucket, Path: "path/new"}})
+		if err != nil {
+			t.Fatalf("unable to add request: %s", err.Error())
+		}
+	}
+	if _, err = pool.RemoveProcessedRequest(ObjectRequestT{Id: "old", Object: objectStorage.ObjectT{Bucket: "bucket", Path: "path/old"}}); err != nil {
+		t.Fatalf("unable to remove request: %s", err.Error())
+	}
+	journal.Close()
+
+	journal, err = NewJournal(journalPath, false)
+	if err != nil {
+		t.Fatalf("unable to open journal: %s", err.Error())
+	}
+	defer journal.Close()
+
+	replayed := NewObjectRequestPool()
+	if err = replayed.SetJournal(journal); err != nil {
+		t.Fatalf("unable to replay journal: %s", err.Error())
+	}
+
+	requests := replayed.GetPool()
+	if len(requests) != 2 {
+		t.Fatalf("replayed %d requests, expected 2: %v", len(requests), requests)
+	}
+	for _, key := range []string{"bucket-a/path/new", "bucket-b/path/new"} {
+		if _, ok := requests[key]; !ok {
+			t.Fatalf("request '%s' not replayed", key)
+		}
+	}
+}
@@ -14,6 +14,9 @@ type ServerInstancesPoolT struct {
 type ServerT struct {
 	Name    string `json:"name"`
 	Address string `json:"address"`
+	Port    string `json:"port,omitempty"`
+	Version string `json:"version,omitempty"`
+	Weight  int    `json:"weight,omitempty"`
 }
 
 func NewServerPool() *ServerInstancesPoolT {
@@ -43,6 +46,20 @@ func (pool *ServerInstancesPoolT) GetServersList() (result []ServerT) {
 	return result
 }
 
+// GetServerByName returns the stored server with the name.
+func (pool *ServerInstancesPoolT) GetServerByName(name string) (server ServerT, ok bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for _, server = range pool.servers {
+		if server.Name == name {
+			return server, true
+		}
+	}
+
+	return ServerT{}, false
+}
+
 func (pool *ServerInstancesPoolT) AddServers(servers []ServerT) {
 	pool.mu.Lock()
 	for _, server := range servers {
@@ -60,5 +77,5 @@ func (pool *ServerInstancesPoolT) RemoveServers(servers []ServerT) {
 }
 
 func (s *ServerT) String() string {
-	return fmt.Sprintf("{name: '%s', adress: '%s'}", s.Name, s.Address)
+	return fmt.Sprintf("{name: '%s', adress: '%s', port: '%s'}", s.Name, s.Address, s.Port)
 }
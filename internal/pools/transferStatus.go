@@ -0,0 +1,120 @@
+package pools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"bot/internal/managers/objectStorage"
+)
+
+const (
+	TransferStateQueued   = "queued"
+	TransferStateRouting  = "routing"
+	TransferStateCopying  = "copying"
+	TransferStateCopied   = "copied"
+	TransferStateRetrying = "retrying"
+	TransferStateSkipped  = "skipped"
+	// TransferStateForwarded is the final state of the requests forwarded
+	// to the hashring instance owning the object
+	TransferStateForwarded = "forwarded"
+	TransferStateRecorded  = "recorded"
+	TransferStateFailed    = "failed"
+)
+
+type TransferStatusPoolT struct {
+	mu       sync.Mutex
+	statuses map[string]TransferStatusT
+}
+
+type TransferStatusT struct {
+	Id        string                `json:"id"`
+	Object    objectStorage.ObjectT `json:"object"`
+	State     string                `json:"state"`
+	Reason    string                `json:"reason,omitempty"`
+	CreatedAt time.Time             `json:"createdAt"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+func NewTransferStatusPool() *TransferStatusPoolT {
+	return &TransferStatusPoolT{
+		statuses: map[string]TransferStatusT{},
+	}
+}
+
+// NewRequestId returns a random identifier for a transfer request.
+func NewRequestId() string {
+	idBytes := make([]byte, 16)
+	rand.Read(idBytes)
+	return hex.EncodeToString(idBytes)
+}
+
+// STATUS POOL FUNCTIONS
+
+func (pool *TransferStatusPoolT) GetStatus(id string) (status TransferStatusT, ok bool) {
+	pool.mu.Lock()
+	status, ok = pool.statuses[id]
+	pool.mu.Unlock()
+
+	return status, ok
+}
+
+// AddStatus registers a new transfer in queued state.
+// An already registered transfer keeps its current status.
+func (pool *TransferStatusPoolT) AddStatus(id string, object objectStorage.ObjectT) {
+	if id == "" {
+		return
+	}
+	now := time.Now()
+
+	pool.mu.Lock()
+	if _, ok := pool.statuses[id]; !ok {
+		pool.statuses[id] = TransferStatusT{
+			Id:        id,
+			Object:    object,
+			State:     TransferStateQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+	pool.mu.Unlock()
+}
+
+// SetState moves a registered transfer to a new state, unknown ids are ignored.
+func (pool *TransferStatusPoolT) SetState(id string, state string, reason string) {
+	pool.mu.Lock()
+	if status, ok := pool.statuses[id]; ok {
+		status.State = state
+		status.Reason = reason
+		status.UpdatedAt = time.Now()
+		pool.statuses[id] = status
+	}
+	pool.mu.Unlock()
+}
+
+// RemoveExpired removes the finished transfers (recorded, skipped, forwarded or failed)
+// not updated since the retention time and returns how many were removed.
+func (pool *TransferStatusPoolT) RemoveExpired(retention time.Duration) (count int) {
+	limit := time.Now().Add(-retention)
+
+	pool.mu.Lock()
+	for id, status := range pool.statuses {
+		if !status.IsFinished() {
+			continue
+		}
+
+		if status.UpdatedAt.Before(limit) {
+			delete(pool.statuses, id)
+			count++
+		}
+	}
+	pool.mu.Unlock()
+
+	return count
+}
+
+func (s *TransferStatusT) IsFinished() bool {
+	return s.State == TransferStateRecorded || s.State == TransferStateSkipped ||
+		s.State == TransferStateForwarded || s.State == TransferStateFailed
+}
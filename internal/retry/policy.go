@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"regexp"
+	"time"
+
+	"bot/api/v1alpha3"
+)
+
+// PolicyT decides if a failed request must be retried and how long
+// to wait before the next attempt.
+type PolicyT struct {
+	maxAttempts     int
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	multiplier      float64
+	jitter          float64
+	retryableErrors []*regexp.Regexp
+}
+
+func NewPolicy(config v1alpha3.RetryConfigT) (p *PolicyT, err error) {
+	p = &PolicyT{
+		maxAttempts:    config.MaxAttempts,
+		initialBackoff: config.InitialBackoff,
+		maxBackoff:     config.MaxBackoff,
+		multiplier:     config.Multiplier,
+		jitter:         config.Jitter,
+	}
+
+	for _, expr := range config.RetryableErrors {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return p, err
+		}
+		p.retryableErrors = append(p.retryableErrors, re)
+	}
+
+	return p, err
+}
+
+// ShouldRetry returns true when the request failed with a retryable error
+// and has not consumed all its attempts yet.
+func (p *PolicyT) ShouldRetry(attempts int, err error) bool {
+	if attempts >= p.maxAttempts {
+		return false
+	}
+
+	return p.IsRetryable(err)
+}
+
+// IsRetryable returns true when the error matches any of the configured
+// expressions, or always when there is not any configured.
+func (p *PolicyT) IsRetryable(err error) bool {
+	if len(p.retryableErrors) == 0 {
+		return true
+	}
+
+	for _, re := range p.retryableErrors {
+		if re.MatchString(err.Error()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Backoff returns the time to wait after the given failed attempt (starting at 1),
+// growing exponentially up to the max backoff with a random jitter applied.
+func (p *PolicyT) Backoff(attempt int) (backoff time.Duration) {
+	backoffF := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if backoffF > float64(p.maxBackoff) {
+		backoffF = float64(p.maxBackoff)
+	}
+
+	if p.jitter > 0 {
+		backoffF += backoffF * p.jitter * (2*rand.Float64() - 1)
+	}
+
+	backoff = time.Duration(backoffF)
+	return backoff
+}
+
+func (p *PolicyT) GetMaxAttempts() int {
+	return p.maxAttempts
+}
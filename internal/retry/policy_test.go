@@ -0,0 +1,109 @@
+package retry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"bot/api/v1alpha3"
+)
+
+func TestShouldRetry(t *testing.T) {
+	tests := []struct {
+		name            string
+		retryableErrors []string
+		attempts        int
+		err             error
+
+		expected bool
+	}{
+		{
+			name:     "any error without retryable errors",
+			attempts: 1,
+			err:      fmt.Errorf("connection reset by peer"),
+			expected: true,
+		},
+		{
+			name:     "all attempts consumed",
+			attempts: 3,
+			err:      fmt.Errorf("connection reset by peer"),
+			expected: false,
+		},
+		{
+			name:            "matching retryable error",
+			retryableErrors: []string{"timeout", "connection reset"},
+			attempts:        2,
+			err:             fmt.Errorf("read: connection reset by peer"),
+			expected:        true,
+		},
+		{
+			name:            "not matching retryable error",
+			retryableErrors: []string{"timeout", "connection reset"},
+			attempts:        1,
+			err:             fmt.Errorf("access denied"),
+			expected:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := NewPolicy(v1alpha3.RetryConfigT{
+				MaxAttempts:     3,
+				RetryableErrors: test.retryableErrors,
+			})
+			if err != nil {
+				t.Fatalf("unable to create policy: %s", err.Error())
+			}
+
+			if retry := p.ShouldRetry(test.attempts, test.err); retry != test.expected {
+				t.Fatalf("should retry is %t, expected %t", retry, test.expected)
+			}
+		})
+	}
+}
+
+func TestNewPolicyInvalidRetryableError(t *testing.T) {
+	_, err := NewPolicy(v1alpha3.RetryConfigT{RetryableErrors: []string{"("}})
+	if err == nil {
+		t.Fatalf("expected invalid expression error")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		jitter  float64
+		attempt int
+
+		expected time.Duration
+	}{
+		{name: "first attempt", attempt: 1, expected: 100 * time.Millisecond},
+		{name: "exponential growth", attempt: 3, expected: 400 * time.Millisecond},
+		{name: "max backoff", attempt: 10, expected: time.Second},
+		{name: "jitter", jitter: 0.5, attempt: 3, expected: 400 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := NewPolicy(v1alpha3.RetryConfigT{
+				MaxAttempts:    10,
+				InitialBackoff: 100 * time.Millisecond,
+				MaxBackoff:     time.Second,
+				Multiplier:     2,
+				Jitter:         test.jitter,
+			})
+			if err != nil {
+				t.Fatalf("unable to create policy: %s", err.Error())
+			}
+
+			// the jitter moves the backoff up to its fraction in both directions
+			low := time.Duration(float64(test.expected) * (1 - test.jitter))
+			high := time.Duration(float64(test.expected) * (1 + test.jitter))
+			for i := 0; i < 100; i++ {
+				if backoff := p.Backoff(test.attempt); backoff < low || backoff > high {
+					t.Fatalf("backoff is %s, expected between %s and %s", backoff, low, high)
+				}
+			}
+		})
+	}
+}